@@ -5,9 +5,11 @@ from tqdm import tqdm
 import os
 # get MONGODB_URI from environment
 MONGODB_URI = os.getenv("MONGODB_URI")
+# the database of the profile, see "sleuth profile show"
+SLEUTH_DATABASE = os.getenv("SLEUTH_DATABASE", "sleuth")
 
 client = pymongo.MongoClient(MONGODB_URI)
-coll   = client.sleuth.articles
+coll   = client[SLEUTH_DATABASE].articles
 
 def text_of(doc):
     bits = [
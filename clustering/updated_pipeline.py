@@ -1,3 +1,5 @@
+import datetime
+import getpass
 import os
 import pymongo
 import torch
@@ -11,8 +13,10 @@ CONF_THRESHOLD = 0.9
 
 # --- DB SETUP ---
 MONGODB_URI = os.getenv("MONGODB_URI")
+SLEUTH_DATABASE = os.getenv("SLEUTH_DATABASE", "sleuth")  # the database of the profile
 client = pymongo.MongoClient(MONGODB_URI)
-coll = client.sleuth.articles
+coll = client[SLEUTH_DATABASE].articles
+history = client[SLEUTH_DATABASE].history
 
 
 # --- UTILS ---
@@ -46,9 +50,9 @@ def build_text(doc):
     # Include body discovery events
     events = doc.get("relevantTimestamps", [])
     for e in events:
-        snippet = e.get("text_snippet", "").strip()
+        snippet = e.get("textSnippet", "").strip()
         loc = e.get("location", "").strip()
-        time_d = e.get("time_detail", "").strip()
+        time_d = e.get("timeDetail", "").strip()
         # append only non-empty components
         if snippet:
             parts.append(snippet)
@@ -70,9 +74,11 @@ docs = list(
             "date": 1,
             "location": 1,
             "victimNames": 1,
-            "relevantTimestamps.text_snippet": 1,
+            "relevantTimestamps.textSnippet": 1,
             "relevantTimestamps.location": 1,
-            "relevantTimestamps.time_detail": 1,
+            "relevantTimestamps.timeDetail": 1,
+            "clusterId": 1,
+            "caseId": 1,
         },
     )
 )
@@ -102,12 +108,36 @@ print("Cluster label counts:", collections.Counter(clusterer.labels_))
 
 # --- UPDATE DB ---
 print("Writing cluster results to MongoDB…")
+now = datetime.datetime.now(datetime.timezone.utc)
+operator = getpass.getuser()
 ops = []
-for idx, doc_id in enumerate(ids):
+changes = []
+for idx, (doc_id, doc) in enumerate(zip(ids, docs)):
     lbl = int(clusterer.labels_[idx])
     conf = float(clusterer.probabilities_[idx])
     # decide caseId: if noise or low-confidence, mark as -1
     case_id = lbl if (lbl != -1 and conf >= CONF_THRESHOLD) else -1
+
+    # record the changed caseId and clusterId in the history of the article, like the CLI does
+    fields = []
+    for field, value in (("caseId", case_id), ("clusterId", lbl)):
+        if field in doc and doc[field] == value:
+            continue
+        change = {"field": field, "new": value}
+        if field in doc:
+            change["old"] = doc[field]
+        fields.append(change)
+    if fields:
+        changes.append(
+            {
+                "articleId": doc_id,
+                "changes": fields,
+                "command": "clustering/updated_pipeline.py",
+                "operator": operator,
+                "changedAt": now,
+            }
+        )
+
     ops.append(
         pymongo.UpdateOne(
             {"_id": doc_id},
@@ -117,7 +147,13 @@ for idx, doc_id in enumerate(ids):
                     "clusterConf": conf,
                     "caseId": case_id,
                     "embed": embeds[idx].tolist(),
-                }
+                    "stages.clustered.state": "done",
+                    "stages.clustered.updatedAt": now,
+                    "stages.clustered.completedAt": now,
+                },
+                "$inc": {"stages.clustered.attempts": 1},
+                "$min": {"stages.clustered.startedAt": now},
+                "$unset": {"stages.clustered.lastError": ""},
             },
         )
     )
@@ -125,5 +161,8 @@ for idx, doc_id in enumerate(ids):
 if ops:
     coll.bulk_write(ops, ordered=False)
     print(f"Updated {len(ops)} documents with clusterId/clusterConf/caseId/embed.")
+    if changes:
+        history.insert_many(changes, ordered=False)
+        print(f"Recorded {len(changes)} changes of caseId/clusterId in the history.")
 else:
     print("No updates to write.")
@@ -126,7 +126,7 @@ def main() -> None:
     MONGODB_URI = os.getenv("MONGODB_URI")
     p = argparse.ArgumentParser(description="Visualise article clusters.")
     p.add_argument("--mongo", default=MONGODB_URI, help="Mongo URI")
-    p.add_argument("--db", default="sleuth", help="database name")
+    p.add_argument("--db", default=os.getenv("SLEUTH_DATABASE", "sleuth"), help="database name")
     p.add_argument("--coll", default="articles", help="collection name")
     p.add_argument("--limit", type=int, default=0, help="max docs (0 = all)")
     p.add_argument(
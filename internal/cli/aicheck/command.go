@@ -1,10 +1,12 @@
 package aicheck
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/profile"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,86 +15,145 @@ import (
 var (
 	use   = "aicheck"
 	short = "Goes through all articles and decides if they should be downloaded"
+
+	// Command flags
+	modelName         string
+	recomputeOutdated bool
 )
 
+// decisionField is the field the decision is stored in, and which its provenance is recorded for.
+const decisionField = "aiSuggestsDownloadingVideo"
+
 var Cmd = &cobra.Command{
 	Use:   use,
 	Short: short,
 	Run:   run,
 }
 
+func init() {
+	Cmd.Flags().StringVarP(&modelName, "model", "m", "llama3.1", "Model to use for deciding if videos should be downloaded")
+	Cmd.Flags().BoolVar(&recomputeOutdated, "recompute-outdated", false, "Check again the articles whose decision was made by another model or prompt, or has no provenance")
+}
+
 func run(cmd *cobra.Command, args []string) {
 
 	ctx := cmd.Context()
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 
-	articles, err := db.Models.FindAllArticlesNotChecked(ctx)
+	filter := bson.M{"aiHasCheckedIfShouldDownloadVideo": false}
+	if recomputeOutdated {
+		// only checked articles have a decision that can be outdated
+		filter = bson.M{"aiHasCheckedIfShouldDownloadVideo": true}
+	}
+	// decisions made by hand are never overwritten
+	db.ExcludeLocked(filter, decisionField)
+	count, err := db.Models.CountArticles(ctx, filter)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to find articles")
+		log.Fatal().Err(err).Msg("failed to count articles")
 	}
-	if len(articles) == 0 {
+	if count == 0 {
 		log.Info().Msg("no articles to check")
 		return
 	}
 
-	log.Info().Int("count", len(articles)).Msg("found articles to check")
-	for _, article := range articles {
-		log.Info().Str("url", article.Url).Msg("checking article")
-		shouldDownload, err := decideIfShouldDownloadVideo(article)
+	log.Info().Int64("count", count).Msg("found articles to check")
+	// Stream the articles in small batches, every article waits for the model
+	articles := db.Models.ScanArticles(ctx, filter, db.ScanOptions{BatchSize: 20, Projection: db.WithoutEmbeddings})
+	for article, err := range articles {
 		if err != nil {
-			log.Err(err).Msg("failed to decide if should download video, skipping for now")
-			continue
-		}
-		log.Info().Str("url", article.Url).Bool("shouldDownload", shouldDownload).Msg("decided if should download video")
-		// set aiHasCheckedIfShouldDownloadVideo to true and shouldDownload to the value we got
-		update := bson.M{
-			"aiHasCheckedIfShouldDownloadVideo": true,
-			"aiSuggestsDownloadingVideo":        shouldDownload,
+			log.Fatal().Err(err).Msg("failed to find articles")
 		}
-		err = db.Models.UpdateArticle(ctx, article.Id, update)
-		if err != nil {
-			log.Err(err).Msg("failed to update article")
+		if recomputeOutdated && article.DerivedBy(decisionField, modelName, profile.Active.Prompts.Screening) {
 			continue
 		}
-		log.Info().Str("url", article.Url).Msg("updated article")
-
+		checkArticle(ctx, article)
 	}
 }
 
-func decideIfShouldDownloadVideo(article *db.Article) (bool, error) {
-
-	systemPrompt := `We are building a dataset on crime cases where bodies were found. I will provide you with a video title and description and you will decide if the video should be downloaded for further processing.
-
-A video would be useful if it may contain information about
+// CheckArticles decides for every article if its video should be downloaded and stores the decision.
+// The articles are updated in place. It returns the number of articles that were checked.
+func CheckArticles(ctx context.Context, articles []*db.Article) int {
+	checked := 0
+	for _, article := range articles {
+		if checkArticle(ctx, article) {
+			checked++
+		}
+	}
+	return checked
+}
 
-- A case where a body may eventually be found
-- A missing person report
-- A solved case about a missing person
+// checkArticle decides if the video of an article should be downloaded and stores the decision.
+// It reports whether the article was checked.
+func checkArticle(ctx context.Context, article *db.Article) bool {
+	if article.Locked(decisionField) {
+		log.Info().Str("url", article.Url).Msg("decision was locked by hand, skipping")
+		return false
+	}
+	log.Info().Str("url", article.Url).Msg("checking article")
+	shouldDownload, response, err := decideIfShouldDownloadVideo(article)
+	if err != nil {
+		log.Err(err).Msg("failed to decide if should download video, skipping for now")
+		db.FinishStage(ctx, article.Id, db.StageScreened, err)
+		return false
+	}
+	log.Info().Str("url", article.Url).Bool("shouldDownload", shouldDownload).Msg("decided if should download video")
+	// set aiHasCheckedIfShouldDownloadVideo to true and shouldDownload to the value we got
+	update := bson.M{
+		"aiHasCheckedIfShouldDownloadVideo": true,
+		decisionField:                       shouldDownload,
+		db.ProvenancePath(decisionField):    db.NewFieldProvenance(modelName, profile.Active.Prompts.Screening, response),
+	}
+	err = db.Models.UpdateArticle(ctx, article.Id, update)
+	db.FinishStage(ctx, article.Id, db.StageScreened, err)
+	if err != nil {
+		log.Err(err).Msg("failed to update article")
+		return false
+	}
+	article.AiHasCheckedIfShouldDownloadVideo = true
+	article.AiSuggestsDownloadingVideo = shouldDownload
+	log.Info().Str("url", article.Url).Msg("updated article")
+	return true
+}
 
-Respond with "true" or "false" depending on if the video should be downloaded (true) or not (false).
-`
+// screenedArticle is what the model is shown of an article.
+type screenedArticle struct {
+	Title       string `json:"title"`
+	Url         string `json:"url"`
+	Date        string `json:"date"`
+	Description string `json:"description"`
+	Provider    string `json:"provider"`
+}
 
-	// stringify struct
-	json, err := json.Marshal(article)
+// decideIfShouldDownloadVideo asks the model whether the video of an article should be downloaded.
+// It returns the decision and the raw response of the model.
+func decideIfShouldDownloadVideo(article *db.Article) (bool, string, error) {
+	// stringify the fields the model decides on
+	json, err := json.Marshal(screenedArticle{
+		Title:       article.Title,
+		Url:         article.Url,
+		Date:        article.Date,
+		Description: article.Description,
+		Provider:    article.Provider,
+	})
 	if err != nil {
 		log.Err(err).Msg("failed to marshal article")
-		return false, err
+		return false, "", err
 	}
 
 	// call local ollama API at http://localhost:11434/v1/chat
-	response, err := CallOllama("llama3.1", systemPrompt, string(json))
+	response, err := CallOllama(modelName, profile.Active.Prompts.Screening.Text, string(json))
 	if err != nil {
 		log.Err(err).Msg("failed to call ollama")
-		return false, err
+		return false, "", err
 	}
 	// if theres more than 5 letters in the response, its probably not a valid response
 	if len(response) > len("false") {
-		return false, fmt.Errorf("response is too long, not a valid response, got %s", response)
+		return false, response, fmt.Errorf("response is too long, not a valid response, got %s", response)
 	}
 	// check if response is true or false
-	return response == "true", nil
+	return response == "true", response, nil
 
 }
@@ -0,0 +1,76 @@
+package article
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	use   = "article"
+	short = "Inspect, correct and delete single articles"
+
+	// Command flags
+	jsonFormat bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+}
+
+func init() {
+	Cmd.PersistentFlags().BoolVarP(&jsonFormat, "json", "j", false, "Output in JSON format")
+	Cmd.AddCommand(getCmd)
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(setCmd)
+	Cmd.AddCommand(editCmd)
+	Cmd.AddCommand(unlockCmd)
+	Cmd.AddCommand(deleteCmd)
+	Cmd.AddCommand(historyCmd)
+}
+
+func connect() {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+}
+
+func printJSON(v any) {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to marshal to JSON")
+	}
+	fmt.Println(string(jsonData))
+}
+
+func editableFields() []string {
+	fields := make([]string, 0, len(db.EditableFields))
+	for field := range db.EditableFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// findArticle finds an article by its ID, or by its url if ref is not an ID.
+func findArticle(ctx context.Context, ref string) *db.Article {
+	var article *db.Article
+	var err error
+	if id, idErr := primitive.ObjectIDFromHex(ref); idErr == nil {
+		article, err = db.Models.FindArticleByID(ctx, id)
+	} else {
+		article, err = db.Models.FindArticleByUrl(ctx, ref)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Str("article", ref).Msg("failed to find article")
+	}
+	return article
+}
@@ -0,0 +1,116 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Command flags
+	keepFiles     bool
+	timestampsDir string
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete <id-or-url>...",
+	Short: "Delete articles with their downloaded video and timestamp files",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runDelete,
+}
+
+func init() {
+	deleteCmd.Flags().BoolVar(&keepFiles, "keep-files", false, "Keep the downloaded video and timestamp files")
+	deleteCmd.Flags().StringVar(&timestampsDir, "timestamps-dir", "./timestamps", "Directory of the timestamp files, as given to ingest-timestamp-metadata")
+}
+
+// articleFiles returns the files that were created for an article.
+func articleFiles(article *db.Article) []string {
+	var files []string
+	if article.VideoPath != "" {
+		files = append(files, article.VideoPath)
+	}
+	return append(files, filepath.Join(timestampsDir, article.Id.Hex()+".json"))
+}
+
+// lastCustodyEntries returns the last entry of the custody log of every recorded file.
+func lastCustodyEntries(ctx context.Context) map[string]*db.CustodyEntry {
+	entries, err := db.Models.FindCustodyEntries(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to read the custody log")
+	}
+	last := map[string]*db.CustodyEntry{}
+	for _, entry := range entries {
+		last[entry.Path] = entry
+	}
+	return last
+}
+
+// recordDisposal records in the custody log that a captured video is deleted, with its hash right before,
+// so that custody verify does not report it as missing. The file must only be removed if it succeeds.
+func recordDisposal(ctx context.Context, article *db.Article, capture *db.CustodyEntry) error {
+	sum, size, err := db.HashFile(capture.Path)
+	if err != nil {
+		return err
+	}
+	disposal := &db.CustodyEntry{
+		Event:      db.CustodyDisposed,
+		ArticleId:  article.Id,
+		SourceUrl:  capture.SourceUrl,
+		MediaUrl:   capture.MediaUrl,
+		Path:       capture.Path,
+		Bytes:      size,
+		SHA256:     sum,
+		CapturedAt: time.Now(),
+	}
+	return db.Models.AppendCustodyEntry(ctx, disposal)
+}
+
+func runDelete(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	connect()
+
+	var custody map[string]*db.CustodyEntry
+	if !keepFiles {
+		custody = lastCustodyEntries(ctx)
+	}
+
+	for _, ref := range args {
+		article := findArticle(ctx, ref)
+		// the article is deleted first, so that it never points to files that are gone
+		if err := db.Models.DeleteArticle(ctx, article.Id); err != nil {
+			log.Fatal().Err(err).Str("id", article.Id.Hex()).Msg("failed to delete article")
+		}
+		fmt.Printf("Deleted %s %s\n", article.Id.Hex(), article.Url)
+		if keepFiles {
+			continue
+		}
+		for _, file := range articleFiles(article) {
+			if capture, found := custody[file]; found && !capture.Disposed() {
+				err := recordDisposal(ctx, article, capture)
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				if err != nil {
+					log.Err(err).Str("file", file).Msg("failed to record the disposal of the video, keeping it")
+					continue
+				}
+			}
+			err := os.Remove(file)
+			switch {
+			case err == nil:
+				fmt.Printf("Removed %s\n", file)
+			case !errors.Is(err, fs.ErrNotExist):
+				log.Err(err).Str("file", file).Msg("failed to remove file")
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package article
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <id-or-url>",
+	Short: "Show every recorded change of an article, oldest first",
+	Long: `Show every recorded change of an article, oldest first: the fields that changed with their old and
+new values, the command that changed them, who ran it and when. Values are shown as they are stored,
+null if a field was not set. The history of a deleted article is shown by its ID, ending with the deletion.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runHistory,
+}
+
+func runHistory(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	connect()
+	// the history of a deleted article is kept, so an ID is looked up without the article
+	id, err := primitive.ObjectIDFromHex(args[0])
+	if err != nil {
+		id = findArticle(ctx, args[0]).Id
+	}
+
+	changes, err := db.Models.FindArticleHistory(ctx, id)
+	if err != nil {
+		log.Fatal().Err(err).Str("id", id.Hex()).Msg("failed to find history")
+	}
+
+	if jsonFormat {
+		if changes == nil {
+			changes = []*db.ArticleChange{}
+		}
+		printJSON(changes)
+		return
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("No changes of %s were recorded\n", id.Hex())
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGED\tCOMMAND\tOPERATOR\tFIELD\tOLD\tNEW")
+	for _, change := range changes {
+		for _, field := range change.Changes {
+			name := field.Field
+			if change.Deleted {
+				name += " (deleted)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				change.ChangedAt.Local().Format(time.DateTime), change.Command, change.Operator, name,
+				truncate(db.FormatValue(field.Old), 40), truncate(db.FormatValue(field.New), 40))
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,239 @@
+package article
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	// Command flags
+	provider string
+	since    string
+	until    string
+	stages   []string
+	text     string
+	limit    int
+)
+
+var getCmd = &cobra.Command{
+	Use:   "get <id-or-url>",
+	Short: "Show an article",
+	Args:  cobra.ExactArgs(1),
+	Run:   runGet,
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the articles that match the filters, oldest first",
+	Long: `List the articles that match every filter, in the order they were found.
+
+--since and --until select when an article was found, as a date (2025-03-01, which --until includes)
+or a time (2025-03-01T15:04:05Z). --stage selects the state of a stage, e.g. --stage downloaded=failed,
+where the state "none" selects articles that have not started the stage. --text searches the title,
+description, url, location and victim names, ignoring case.`,
+	Args: cobra.NoArgs,
+	Run:  runList,
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&provider, "provider", "p", "", "Only list articles of this provider")
+	listCmd.Flags().StringVar(&since, "since", "", "Only list articles found at or after this date or time")
+	listCmd.Flags().StringVar(&until, "until", "", "Only list articles found at or before this date or time")
+	listCmd.Flags().StringArrayVar(&stages, "stage", nil, "Only list articles whose stage is in a state, as stage=state (can be repeated)")
+	listCmd.Flags().StringVarP(&text, "text", "t", "", "Only list articles that contain this text")
+	listCmd.Flags().IntVarP(&limit, "limit", "l", 50, "Number of articles to list (0 means no limit)")
+}
+
+// listFilter selects the articles list shows. The conditions the store cannot evaluate are checked on every article.
+type listFilter struct {
+	provider string
+	since    time.Time
+	until    time.Time
+	stages   map[db.Stage]string
+	text     string
+}
+
+// parseTime parses a date or a time. A date given as the end of a range includes the whole day.
+func parseTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date like 2025-03-01 or a time like 2025-03-01T15:04:05Z, got %q", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+func newListFilter(provider, since, until string, stages []string, text string) (listFilter, error) {
+	f := listFilter{provider: provider, stages: map[db.Stage]string{}, text: strings.ToLower(text)}
+	var err error
+	if since != "" {
+		if f.since, err = parseTime(since, false); err != nil {
+			return f, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if until != "" {
+		if f.until, err = parseTime(until, true); err != nil {
+			return f, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	states := []string{string(db.StageDone), string(db.StageStarted), string(db.StageFailed), "none"}
+	for _, condition := range stages {
+		stage, state, found := strings.Cut(condition, "=")
+		if !found {
+			return f, fmt.Errorf("expected stage=state, got %q", condition)
+		}
+		if !slices.Contains(db.Stages, db.Stage(stage)) {
+			return f, fmt.Errorf("unknown stage %q", stage)
+		}
+		if !slices.Contains(states, state) {
+			return f, fmt.Errorf("unknown state %q, expected one of %s", state, strings.Join(states, ", "))
+		}
+		f.stages[db.Stage(stage)] = state
+	}
+	return f, nil
+}
+
+// storeFilter returns the conditions the store evaluates.
+func (f listFilter) storeFilter() bson.M {
+	filter := bson.M{}
+	if f.provider != "" {
+		filter["provider"] = f.provider
+	}
+	for stage, state := range f.stages {
+		if state == "none" {
+			filter["stages."+string(stage)] = bson.M{"$exists": false}
+		} else {
+			filter["stages."+string(stage)+".state"] = state
+		}
+	}
+	return filter
+}
+
+// matches checks the conditions the store does not evaluate. ObjectIDs start with the time the article was found.
+func (f listFilter) matches(article *db.Article) bool {
+	found := article.Id.Timestamp()
+	if !f.since.IsZero() && found.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && found.After(f.until) {
+		return false
+	}
+	if f.text == "" {
+		return true
+	}
+	fields := []string{article.Title, article.Description, article.Url, article.Location}
+	fields = append(fields, article.VictimNames...)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), f.text) {
+			return true
+		}
+	}
+	return false
+}
+
+func runList(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	filter, err := newListFilter(provider, since, until, stages, text)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid filter")
+	}
+	connect()
+
+	articles := []*db.Article{}
+	scan := db.Models.ScanArticles(ctx, filter.storeFilter(), db.ScanOptions{Projection: db.WithoutEmbeddings})
+	for article, err := range scan {
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to find articles")
+		}
+		if !filter.matches(article) {
+			continue
+		}
+		articles = append(articles, article)
+		if limit > 0 && len(articles) == limit {
+			break
+		}
+	}
+
+	if jsonFormat {
+		printJSON(articles)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tFOUND\tPROVIDER\tTITLE\tURL")
+	for _, article := range articles {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			article.Id.Hex(), article.Id.Timestamp().Local().Format(time.DateTime), article.Provider, truncate(article.Title, 60), article.Url)
+	}
+	w.Flush()
+	if limit > 0 && len(articles) == limit {
+		fmt.Printf("\nShowing the first %d articles, use --limit to show more\n", limit)
+	}
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+func runGet(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	connect()
+	article := findArticle(ctx, args[0])
+
+	if jsonFormat {
+		printJSON(article)
+		return
+	}
+
+	locked := func(field string) string {
+		if article.Locked(field) {
+			return " (locked)"
+		}
+		return ""
+	}
+	fmt.Printf("Article:      %s\n", article.Id.Hex())
+	fmt.Printf("Found:        %s\n", article.Id.Timestamp().Local().Format(time.DateTime))
+	fmt.Printf("Provider:     %s\n", article.Provider)
+	fmt.Printf("Url:          %s\n", article.Url)
+	fmt.Printf("Title:        %s%s\n", article.Title, locked("title"))
+	fmt.Printf("Date:         %s%s\n", article.Date, locked("date"))
+	fmt.Printf("Description:  %s%s\n", article.Description, locked("description"))
+	fmt.Printf("Approved:     %t%s\n", article.AiSuggestsDownloadingVideo, locked("aiSuggestsDownloadingVideo"))
+	fmt.Printf("Video:        %s\n", article.VideoPath)
+	fmt.Printf("Victim names: %s%s\n", strings.Join(article.VictimNames, "; "), locked("victimNames"))
+	fmt.Printf("Location:     %s%s\n", article.Location, locked("location"))
+	fmt.Printf("Case:         %d%s\n", article.CaseId, locked("caseId"))
+	fmt.Printf("Timestamps:   %d\n", len(article.RelevantTimestamps))
+	fmt.Printf("Searches:     %d\n\n", len(article.Provenance))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STAGE\tSTATE\tATTEMPTS\tUPDATED\tLAST ERROR")
+	for _, stage := range db.Stages {
+		status, found := article.Stages[stage]
+		if !found {
+			fmt.Fprintf(w, "%s\t-\t\t\t\n", stage)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", stage, status.State, status.Attempts, status.UpdatedAt.Local().Format(time.DateTime), status.LastError)
+	}
+	w.Flush()
+}
@@ -0,0 +1,48 @@
+package article
+
+import (
+	"testing"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestListFilter(t *testing.T) {
+	filter, err := newListFilter("cnn", "2025-03-01", "2025-03-02", []string{"downloaded=failed", "clustered=none"}, "LAKE")
+	require.NoError(t, err)
+	require.Equal(t, bson.M{
+		"provider":                "cnn",
+		"stages.downloaded.state": "failed",
+		"stages.clustered":        bson.M{"$exists": false},
+	}, filter.storeFilter())
+
+	foundAt := func(t time.Time) primitive.ObjectID { return primitive.NewObjectIDFromTimestamp(t) }
+	inRange := time.Date(2025, 3, 2, 23, 0, 0, 0, time.Local)
+	require.True(t, filter.matches(&db.Article{Id: foundAt(inRange), Title: "Body found in lake"}))
+	require.True(t, filter.matches(&db.Article{Id: foundAt(inRange), VictimNames: []string{"Jane Lake"}}))
+	require.False(t, filter.matches(&db.Article{Id: foundAt(inRange), Title: "Body found in woods"}))
+	require.False(t, filter.matches(&db.Article{Id: foundAt(inRange.AddDate(0, 0, 1)), Title: "Body found in lake"}))
+
+	_, err = newListFilter("", "", "", []string{"downloaded=stuck"}, "")
+	require.Error(t, err)
+	_, err = newListFilter("", "", "", []string{"uploaded=done"}, "")
+	require.Error(t, err)
+	_, err = newListFilter("", "last week", "", nil, "")
+	require.Error(t, err)
+}
+
+func TestChangedFields(t *testing.T) {
+	before := newEditableArticle(&db.Article{Title: "Body found", Location: "Unknown"})
+	after := before
+	after.Location = "Lake Tahoe"
+	after.VictimNames = []string{"Jane Doe"}
+	require.Equal(t, map[string]any{"location": "Lake Tahoe", "victimNames": []string{"Jane Doe"}}, changedFields(before, after))
+
+	// every field that can be edited can also be set
+	for field := range changedFields(editableArticle{}, editableArticle{Title: "a", Date: "a", Description: "a", Location: "a", VictimNames: []string{"a"}, CaseId: 1, AiSuggestsDownloadingVideo: true}) {
+		require.Contains(t, db.EditableFields, field)
+	}
+}
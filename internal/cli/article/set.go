@@ -0,0 +1,239 @@
+package article
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Set by the --lock flag
+var lock bool
+
+var setCmd = &cobra.Command{
+	Use:   "set <id-or-url> <field=value>...",
+	Short: "Set fields of an article by hand",
+	Long: `Set fields of an article by hand, e.g. to correct a value a model derived. Lists are separated
+by semicolons, e.g. victimNames="Jane Doe; John Doe". With --lock the values are locked, and the
+commands that derive the fields skip them until they are unlocked. Setting a locked field keeps it locked.
+
+Editable fields: ` + strings.Join(editableFields(), ", "),
+	Args: cobra.MinimumNArgs(2),
+	Run:  runSet,
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit <id-or-url>",
+	Short: "Edit the fields of an article in $EDITOR",
+	Long: `Open the editable fields of an article as JSON in $EDITOR, and set the fields that were changed
+when the editor exits, like set does.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runEdit,
+}
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock <id-or-url> <field>...",
+	Short: "Let the commands that derive the fields overwrite them again",
+	Args:  cobra.MinimumNArgs(2),
+	Run:   runUnlock,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{setCmd, editCmd} {
+		cmd.Flags().BoolVar(&lock, "lock", false, "Lock the values, so that no command overwrites them")
+	}
+}
+
+// setFields sets fields of an article by hand and records who set them. Locked fields stay locked.
+func setFields(ctx context.Context, article *db.Article, values map[string]any) {
+	// the operator who runs the command sets the values
+	setBy := db.ActorFrom(ctx).Operator
+	now := time.Now()
+	update := bson.M{}
+	fields := make([]string, 0, len(values))
+	for field, value := range values {
+		fields = append(fields, field)
+		update[field] = value
+
+		override := db.FieldOverride{SetBy: setBy, SetAt: now}
+		if previous := article.Overrides[field]; previous.Locked {
+			override.Locked = true
+			override.LockedAt = previous.LockedAt
+		} else if lock {
+			override.Locked = true
+			override.LockedAt = now
+		}
+		update[db.OverridePath(field)] = override
+		// the value is no longer the one a model derived
+		if _, derived := article.AiProvenance[field]; derived {
+			update[db.ProvenancePath(field)] = nil
+		}
+
+		// a decision made by hand does not need to be checked by a model
+		if field == "aiSuggestsDownloadingVideo" {
+			update["aiHasCheckedIfShouldDownloadVideo"] = true
+		}
+	}
+
+	if err := db.Models.UpdateArticle(ctx, article.Id, update); err != nil {
+		log.Fatal().Err(err).Str("id", article.Id.Hex()).Msg("failed to update article")
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		status := "unlocked"
+		if update[db.OverridePath(field)].(db.FieldOverride).Locked {
+			status = "locked"
+		}
+		fmt.Printf("Set %s of %s (%s)\n", field, article.Id.Hex(), status)
+	}
+}
+
+func runSet(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	connect()
+	article := findArticle(ctx, args[0])
+
+	values := map[string]any{}
+	for _, assignment := range args[1:] {
+		field, value, found := strings.Cut(assignment, "=")
+		if !found {
+			log.Fatal().Str("argument", assignment).Msg("expected field=value")
+		}
+		parsed, err := db.ParseFieldValue(field, value)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse value")
+		}
+		values[field] = parsed
+	}
+	setFields(ctx, article, values)
+}
+
+// editableArticle are the fields edit opens in the editor. The JSON names are the fields' names in the database.
+type editableArticle struct {
+	Title                      string   `json:"title"`
+	Date                       string   `json:"date"`
+	Description                string   `json:"description"`
+	Location                   string   `json:"location"`
+	VictimNames                []string `json:"victimNames"`
+	CaseId                     int32    `json:"caseId"`
+	AiSuggestsDownloadingVideo bool     `json:"aiSuggestsDownloadingVideo"`
+}
+
+func newEditableArticle(article *db.Article) editableArticle {
+	victimNames := article.VictimNames
+	if victimNames == nil {
+		victimNames = []string{}
+	}
+	return editableArticle{
+		Title:                      article.Title,
+		Date:                       article.Date,
+		Description:                article.Description,
+		Location:                   article.Location,
+		VictimNames:                victimNames,
+		CaseId:                     article.CaseId,
+		AiSuggestsDownloadingVideo: article.AiSuggestsDownloadingVideo,
+	}
+}
+
+// changedFields returns the value of every field that differs between before and after, by the field's name.
+func changedFields(before, after editableArticle) map[string]any {
+	changed := map[string]any{}
+	b, a := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := range b.NumField() {
+		if !reflect.DeepEqual(b.Field(i).Interface(), a.Field(i).Interface()) {
+			name, _, _ := strings.Cut(b.Type().Field(i).Tag.Get("json"), ",")
+			changed[name] = a.Field(i).Interface()
+		}
+	}
+	return changed
+}
+
+func runEdit(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	connect()
+	article := findArticle(ctx, args[0])
+
+	before := newEditableArticle(article)
+	data, err := json.MarshalIndent(before, "", "  ")
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to marshal article")
+	}
+	file, err := os.CreateTemp("", "sleuth-article-"+article.Id.Hex()+"-*.json")
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create file to edit")
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Fatal().Err(err).Msg("failed to write file to edit")
+	}
+	file.Close()
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	// the editor can come with arguments, e.g. "code --wait"
+	editorArgs := append(strings.Fields(editor), file.Name())
+	editorCmd := exec.Command(editorArgs[0], editorArgs[1:]...)
+	editorCmd.Stdin, editorCmd.Stdout, editorCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		log.Fatal().Err(err).Str("editor", editor).Msg("editor failed")
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to read edited file")
+	}
+	var after editableArticle
+	decoder := json.NewDecoder(bytes.NewReader(edited))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&after); err != nil {
+		log.Fatal().Err(err).Msg("invalid JSON, nothing was changed")
+	}
+
+	values := changedFields(before, after)
+	if len(values) == 0 {
+		fmt.Println("Nothing was changed")
+		return
+	}
+	setFields(ctx, article, values)
+}
+
+func runUnlock(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	connect()
+	article := findArticle(ctx, args[0])
+
+	update := bson.M{}
+	for _, field := range args[1:] {
+		if !article.Locked(field) {
+			fmt.Printf("%s of %s is not locked\n", field, article.Id.Hex())
+			continue
+		}
+		override := article.Overrides[field]
+		override.Locked = false
+		override.LockedAt = time.Time{}
+		update[db.OverridePath(field)] = override
+	}
+	if len(update) == 0 {
+		return
+	}
+
+	if err := db.Models.UpdateArticle(ctx, article.Id, update); err != nil {
+		log.Fatal().Err(err).Str("id", article.Id.Hex()).Msg("failed to update article")
+	}
+	for path := range update {
+		fmt.Printf("Unlocked %s of %s\n", strings.TrimPrefix(path, db.OverridePath("")), article.Id.Hex())
+	}
+}
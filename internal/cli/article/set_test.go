@@ -0,0 +1,34 @@
+package article
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetFields(t *testing.T) {
+	db.Models = db.NewMemory()
+	t.Cleanup(func() { db.Models = nil })
+	ctx := db.WithActor(context.Background(), db.Actor{Command: "sleuth article set", Operator: "jdoe"})
+
+	article := &db.Article{
+		Url:      "https://www.cnn.com/videos/body-found",
+		Location: "Unknown",
+		AiProvenance: map[string]db.FieldProvenance{
+			"location":    db.NewFieldProvenance("llama3.2", db.Prompt{Version: 1, Text: "Where?"}, "Unknown"),
+			"victimNames": db.NewFieldProvenance("llama3.2", db.Prompt{Version: 1, Text: "Who?"}, "Jane Doe"),
+		},
+	}
+	require.NoError(t, db.Models.CreateArticle(ctx, article))
+
+	setFields(ctx, article, map[string]any{"location": "Lake Tahoe"})
+	stored, err := db.Models.FindArticleByID(ctx, article.Id)
+	require.NoError(t, err)
+	require.Equal(t, "Lake Tahoe", stored.Location)
+	require.Equal(t, "jdoe", stored.Overrides["location"].SetBy)
+	require.Empty(t, stored.AiProvenance["location"].Model)
+	require.False(t, stored.DerivedBy("location", "llama3.2", db.Prompt{Version: 1, Text: "Where?"}))
+	require.Equal(t, "llama3.2", stored.AiProvenance["victimNames"].Model)
+}
@@ -2,22 +2,45 @@ package cli
 
 import (
 	"os"
+	"os/user"
 
 	"github.com/giraffesyo/sleuth/internal/cli/aicheck"
+	"github.com/giraffesyo/sleuth/internal/cli/article"
+	crawlRelated "github.com/giraffesyo/sleuth/internal/cli/crawl_related"
 	"github.com/giraffesyo/sleuth/internal/cli/csv"
+	"github.com/giraffesyo/sleuth/internal/cli/custody"
+	"github.com/giraffesyo/sleuth/internal/cli/database"
 	determineLocation "github.com/giraffesyo/sleuth/internal/cli/determine_location"
 	determineVictim "github.com/giraffesyo/sleuth/internal/cli/determine_victim"
 	downloadVideos "github.com/giraffesyo/sleuth/internal/cli/download_videos"
+	expandTemplate "github.com/giraffesyo/sleuth/internal/cli/expand_template"
 	generateQueries "github.com/giraffesyo/sleuth/internal/cli/generate_queries"
 	ingestTimestamps "github.com/giraffesyo/sleuth/internal/cli/ingest_timestamps"
+	"github.com/giraffesyo/sleuth/internal/cli/profiles"
+	"github.com/giraffesyo/sleuth/internal/cli/runs"
 	"github.com/giraffesyo/sleuth/internal/cli/search"
 	showQueries "github.com/giraffesyo/sleuth/internal/cli/show_queries"
+	"github.com/giraffesyo/sleuth/internal/cli/status"
+	"github.com/giraffesyo/sleuth/internal/cli/watch"
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/profile"
+	"github.com/giraffesyo/sleuth/internal/version"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 // Set by the --verbose flag
 var VerboseLogging bool
 
+// Set by the --dry-run flag
+var dryRun bool
+
+// Set by the --operator flag
+var operator string
+
+// Set by the --profile flag
+var profileName string
+
 var (
 	use   = "sleuth"
 	short = "The Sleuth CLI"
@@ -26,9 +49,31 @@ var (
 var RootCmd = &cobra.Command{
 	Use:               use,
 	Short:             short,
-	Version:           "v0.0.1",
+	Version:           version.Version,
 	DisableAutoGenTag: true,
 	Run:               run,
+	PersistentPreRun:  persistentPreRun,
+}
+
+// persistentPreRun runs before every command. It loads the profile first, as it selects the database
+// and the prompts of the command. The history of every article the command changes
+// records the command and its operator. A dry run connects to an empty in-memory database first,
+// so that the command uses it instead of the configured one.
+func persistentPreRun(cmd *cobra.Command, args []string) {
+	p, err := profile.Load(profile.Dir(), profileName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load profile")
+	}
+	profile.Active = p
+	db.DatabaseName = p.Database
+
+	cmd.SetContext(db.WithActor(cmd.Context(), db.Actor{Command: cmd.CommandPath(), Operator: operator}))
+	if !dryRun {
+		return
+	}
+	if err := db.Connect("memory://"); err != nil {
+		log.Fatal().Err(err).Msg("failed to start in-memory database")
+	}
 }
 
 func run(cmd *cobra.Command, args []string) {
@@ -44,6 +89,9 @@ func run(cmd *cobra.Command, args []string) {
 }
 
 func init() {
+	RootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Use an empty in-memory database that is discarded on exit instead of the configured one")
+	RootCmd.PersistentFlags().StringVar(&operator, "operator", currentUser(), "Who runs the command, recorded in the history of the articles it changes")
+	RootCmd.PersistentFlags().StringVar(&profileName, "profile", defaultProfile(), "Investigation profile that selects the database, prompts, seeds and providers (SLEUTH_PROFILE)")
 	RootCmd.AddCommand(search.Cmd)
 	RootCmd.AddCommand(aicheck.Cmd)
 	RootCmd.AddCommand(csv.Cmd)
@@ -53,4 +101,32 @@ func init() {
 	RootCmd.AddCommand(determineVictim.Cmd)
 	RootCmd.AddCommand(determineLocation.Cmd)
 	RootCmd.AddCommand(showQueries.Cmd)
+	RootCmd.AddCommand(crawlRelated.Cmd)
+	RootCmd.AddCommand(expandTemplate.Cmd)
+	RootCmd.AddCommand(watch.Cmd)
+	RootCmd.AddCommand(runs.Cmd)
+	RootCmd.AddCommand(database.Cmd)
+	RootCmd.AddCommand(status.Cmd)
+	RootCmd.AddCommand(article.Cmd)
+	RootCmd.AddCommand(custody.Cmd)
+	RootCmd.AddCommand(profiles.Cmd)
+}
+
+// defaultProfile returns the profile selected by SLEUTH_PROFILE, or the built-in one.
+func defaultProfile() string {
+	if name := os.Getenv("SLEUTH_PROFILE"); name != "" {
+		return name
+	}
+	return profile.DefaultName
+}
+
+// currentUser returns the name of the user running sleuth.
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
 }
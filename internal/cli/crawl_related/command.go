@@ -0,0 +1,37 @@
+package crawlrelated
+
+import (
+	"github.com/giraffesyo/sleuth/internal/sleuth"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	use   = "crawl-related"
+	short = "Discover related videos linked from articles approved by AI check"
+	long  = `Visit every article where the AI check suggested downloading the video and save the related
+and up next videos linked from its page as new articles. Each new article records the article it
+was discovered via. Discovered articles are crawled in turn until the depth limit is reached.`
+
+	// Command flags
+	depth   int
+	recrawl bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+	Long:  long,
+	Run:   run,
+}
+
+func init() {
+	Cmd.Flags().IntVarP(&depth, "depth", "d", 1, "Maximum number of related-video hops from an approved article")
+	Cmd.Flags().BoolVar(&recrawl, "recrawl", false, "Crawl articles again even if their related videos were already extracted")
+}
+
+func run(cmd *cobra.Command, args []string) {
+	if err := sleuth.CrawlRelated(cmd.Context(), depth, recrawl); err != nil {
+		log.Fatal().Err(err).Msg("failed to crawl related videos")
+	}
+}
@@ -10,13 +10,14 @@ import (
 	"github.com/giraffesyo/sleuth/internal/db"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 var outputFile string
 
 var (
 	use   = "csv"
-	short = "Export articles to CSV format"
+	short = "Export articles to CSV format, or import corrections from such a CSV"
 )
 
 func long() string {
@@ -33,6 +34,33 @@ func timestampsToJSON(timestamps []db.RelevantTimestamp) string {
 	return string(b)
 }
 
+// header are the columns of the CSV, which import expects in the same order.
+var header = []string{
+	"ID", "Title", "URL", "Date", "Description", "Provider",
+	"AI Checked", "AI Suggests Download", "Video URL", "Video Path",
+	"Victim Names", "Location", "Case ID", "Relevant Timestamps",
+}
+
+// articleRow returns the row of an article, with a value for every column of the header.
+func articleRow(article *db.Article) []string {
+	return []string{
+		article.Id.Hex(),
+		article.Title,
+		article.Url,
+		article.Date,
+		article.Description,
+		article.Provider,
+		fmt.Sprintf("%t", article.AiHasCheckedIfShouldDownloadVideo),
+		fmt.Sprintf("%t", article.AiSuggestsDownloadingVideo),
+		article.VideoUrl,
+		article.VideoPath,
+		strings.Join(article.VictimNames, ", "),
+		article.Location,
+		fmt.Sprintf("%d", article.CaseId),
+		timestampsToJSON(article.RelevantTimestamps),
+	}
+}
+
 var Cmd = &cobra.Command{
 	Use:   use,
 	Short: short,
@@ -42,23 +70,23 @@ var Cmd = &cobra.Command{
 
 func run(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 
-	// Fetch all articles from the database
-	articles, err := db.Models.FindAllArticles(ctx)
+	// Count the articles first, they are streamed from the database while writing
+	count, err := db.Models.CountArticles(ctx, bson.M{})
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to fetch articles")
+		log.Fatal().Err(err).Msg("failed to count articles")
 	}
 
-	if len(articles) == 0 {
+	if count == 0 {
 		log.Info().Msg("no articles found in database")
 		return
 	}
 
-	log.Info().Int("count", len(articles)).Msg("found articles")
+	log.Info().Int64("count", count).Msg("found articles")
 
 	// Determine where to write the CSV data
 	var output *os.File
@@ -79,44 +107,31 @@ func run(cmd *cobra.Command, args []string) {
 	defer writer.Flush()
 
 	// Write header row
-	header := []string{
-		"ID", "Title", "URL", "Date", "Description", "Provider",
-		"AI Checked", "AI Suggests Download", "Video URL", "Video Path",
-		"Victim Names", "Location", "Case ID", "Relevant Timestamps",
-	}
 	if err := writer.Write(header); err != nil {
 		log.Fatal().Err(err).Msg("error writing CSV header")
 	}
 
 	// Write article data
-	for _, article := range articles {
-		row := []string{
-			article.Id.Hex(),
-			article.Title,
-			article.Url,
-			article.Date,
-			article.Description,
-			article.Provider,
-			fmt.Sprintf("%t", article.AiHasCheckedIfShouldDownloadVideo),
-			fmt.Sprintf("%t", article.AiSuggestsDownloadingVideo),
-			article.VideoUrl,
-			article.VideoPath,
-			strings.Join(article.VictimNames, ", "),
-			article.Location,
-			fmt.Sprintf("%d", article.CaseId),
-			timestampsToJSON(article.RelevantTimestamps),
+	written := 0
+	articles := db.Models.ScanArticles(ctx, bson.M{}, db.ScanOptions{Projection: db.WithoutEmbeddings})
+	for article, err := range articles {
+		if err != nil {
+			writer.Flush()
+			log.Fatal().Err(err).Int("written", written).Msg("failed to read articles")
 		}
-		if err := writer.Write(row); err != nil {
+		if err := writer.Write(articleRow(article)); err != nil {
 			log.Error().Err(err).Str("url", article.Url).Msg("error writing article to CSV")
 			continue
 		}
+		written++
 	}
 
 	if outputFile != "" {
-		log.Info().Str("file", outputFile).Int("articles", len(articles)).Msg("CSV export complete")
+		log.Info().Str("file", outputFile).Int("articles", written).Msg("CSV export complete")
 	}
 }
 
 func init() {
 	Cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (if not provided, outputs to stdout)")
+	Cmd.AddCommand(importCmd)
 }
@@ -0,0 +1,237 @@
+package csv
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Set by the --pipeline-columns flag
+var pipelineColumns bool
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Update articles from a CSV written by the csv command",
+	Long: `Read a CSV with the columns the csv command writes, e.g. after correcting it in a spreadsheet, and
+update the article of every row, found by its ID or else by its url. Only the editable fields that
+differ are written, and they are recorded as set by hand, like article set does. The columns the
+pipeline fills in, such as Video Path and Relevant Timestamps, are left as stored unless
+--pipeline-columns is given. Rows of articles that are not stored are created, keeping their ID.
+
+Victim names are separated by commas and Relevant Timestamps is a JSON array. Every row is checked
+before anything is written.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runImport,
+}
+
+func init() {
+	importCmd.Flags().BoolVar(&pipelineColumns, "pipeline-columns", false, "Also write the URL, Provider, AI Checked, Video URL, Video Path and Relevant Timestamps columns")
+}
+
+// readRows reads the rows of a CSV and checks that its header is the one export writes.
+func readRows(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("the CSV is empty")
+	}
+	// spreadsheets often save CSVs with a byte order mark
+	records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	if !slices.Equal(records[0], header) {
+		return nil, fmt.Errorf("expected the columns %s, got %s", strings.Join(header, ", "), strings.Join(records[0], ", "))
+	}
+	return records[1:], nil
+}
+
+// parseRow parses a row written by articleRow back into an article. The ID is zero if the row has none.
+func parseRow(row []string) (*db.Article, error) {
+	article := &db.Article{
+		Title:       row[1],
+		Url:         strings.TrimSpace(row[2]),
+		Date:        row[3],
+		Description: row[4],
+		Provider:    row[5],
+		VideoUrl:    row[8],
+		VideoPath:   row[9],
+		Location:    row[11],
+	}
+	var err error
+	if id := strings.TrimSpace(row[0]); id != "" {
+		if article.Id, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, fmt.Errorf("invalid ID %q", id)
+		}
+	}
+	if article.Url == "" {
+		return nil, errors.New("the URL is empty")
+	}
+	if article.AiHasCheckedIfShouldDownloadVideo, err = strconv.ParseBool(strings.TrimSpace(row[6])); err != nil {
+		return nil, fmt.Errorf("invalid AI Checked %q", row[6])
+	}
+	if article.AiSuggestsDownloadingVideo, err = strconv.ParseBool(strings.TrimSpace(row[7])); err != nil {
+		return nil, fmt.Errorf("invalid AI Suggests Download %q", row[7])
+	}
+	for _, name := range strings.Split(row[10], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			article.VictimNames = append(article.VictimNames, name)
+		}
+	}
+	if caseId := strings.TrimSpace(row[12]); caseId != "" {
+		n, err := strconv.ParseInt(caseId, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Case ID %q", row[12])
+		}
+		article.CaseId = int32(n)
+	}
+	if timestamps := strings.TrimSpace(row[13]); timestamps != "" {
+		if err := json.Unmarshal([]byte(timestamps), &article.RelevantTimestamps); err != nil {
+			return nil, fmt.Errorf("invalid Relevant Timestamps: %w", err)
+		}
+	}
+	return article, nil
+}
+
+// changedFields returns the fields of the stored article that differ from the ones of the row, by their names in the database.
+// Only the editable fields are compared, unless pipelineColumns is set, which also compares the columns the pipeline fills in.
+func changedFields(stored, row *db.Article, pipelineColumns bool) bson.M {
+	changed := bson.M{}
+	setIf := func(field string, differs bool, value any) {
+		if differs {
+			changed[field] = value
+		}
+	}
+	setIf("title", stored.Title != row.Title, row.Title)
+	setIf("date", stored.Date != row.Date, row.Date)
+	setIf("description", stored.Description != row.Description, row.Description)
+	setIf("aiSuggestsDownloadingVideo", stored.AiSuggestsDownloadingVideo != row.AiSuggestsDownloadingVideo, row.AiSuggestsDownloadingVideo)
+	setIf("victimNames", !slices.Equal(stored.VictimNames, row.VictimNames), row.VictimNames)
+	setIf("location", stored.Location != row.Location, row.Location)
+	setIf("caseId", stored.CaseId != row.CaseId, row.CaseId)
+	if !pipelineColumns {
+		return changed
+	}
+	setIf("url", stored.Url != row.Url, row.Url)
+	setIf("provider", stored.Provider != row.Provider, row.Provider)
+	setIf("aiHasCheckedIfShouldDownloadVideo", stored.AiHasCheckedIfShouldDownloadVideo != row.AiHasCheckedIfShouldDownloadVideo, row.AiHasCheckedIfShouldDownloadVideo)
+	setIf("videoUrl", stored.VideoUrl != row.VideoUrl, row.VideoUrl)
+	setIf("videoPath", stored.VideoPath != row.VideoPath, row.VideoPath)
+	setIf("relevantTimestamps", !slices.Equal(stored.RelevantTimestamps, row.RelevantTimestamps), row.RelevantTimestamps)
+	return changed
+}
+
+// findStored finds the stored article of a row, by its ID or else by its url, and returns nil if there is none.
+func findStored(ctx context.Context, row *db.Article) (*db.Article, error) {
+	if !row.Id.IsZero() {
+		article, err := db.Models.FindArticleByID(ctx, row.Id)
+		if !errors.Is(err, db.ErrArticleNotFound) {
+			return article, err
+		}
+	}
+	article, err := db.Models.FindArticleByUrl(ctx, row.Url)
+	if errors.Is(err, db.ErrArticleNotFound) {
+		return nil, nil
+	}
+	return article, err
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	file, err := os.Open(args[0])
+	if err != nil {
+		log.Fatal().Err(err).Str("file", args[0]).Msg("failed to open CSV")
+	}
+	defer file.Close()
+
+	rows, err := readRows(file)
+	if err != nil {
+		log.Fatal().Err(err).Str("file", args[0]).Msg("failed to read CSV")
+	}
+	articles := make([]*db.Article, len(rows))
+	for i, row := range rows {
+		if articles[i], err = parseRow(row); err != nil {
+			log.Fatal().Err(err).Int("row", i+1).Msg("invalid row, nothing was imported")
+		}
+	}
+
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+
+	// the operator who runs the command corrected the values
+	setBy := db.ActorFrom(ctx).Operator
+
+	var created, updated, unchanged, failed int
+	for i, row := range articles {
+		number := i + 1
+		stored, err := findStored(ctx, row)
+		if err != nil {
+			log.Error().Err(err).Int("row", number).Msg("failed to find article")
+			failed++
+			continue
+		}
+
+		if stored == nil {
+			if err := db.Models.CreateArticle(ctx, row); err != nil {
+				log.Error().Err(err).Int("row", number).Str("url", row.Url).Msg("failed to create article")
+				failed++
+				continue
+			}
+			fmt.Printf("Created %s %s\n", row.Id.Hex(), row.Url)
+			created++
+			continue
+		}
+
+		update := changedFields(stored, row, pipelineColumns)
+		if len(update) == 0 {
+			unchanged++
+			continue
+		}
+		fields := slices.Sorted(maps.Keys(update))
+		now := time.Now()
+		for _, field := range fields {
+			if _, editable := db.EditableFields[field]; !editable {
+				continue
+			}
+			// a correction keeps the field locked, like article set does
+			override := db.FieldOverride{SetBy: setBy, SetAt: now}
+			if previous := stored.Overrides[field]; previous.Locked {
+				override.Locked = true
+				override.LockedAt = previous.LockedAt
+			}
+			update[db.OverridePath(field)] = override
+			if _, derived := stored.AiProvenance[field]; derived {
+				update[db.ProvenancePath(field)] = nil
+			}
+		}
+		if err := db.Models.UpdateArticle(ctx, stored.Id, update); err != nil {
+			log.Error().Err(err).Int("row", number).Str("id", stored.Id.Hex()).Msg("failed to update article")
+			failed++
+			continue
+		}
+		fmt.Printf("Updated %s: %s\n", stored.Id.Hex(), strings.Join(fields, ", "))
+		updated++
+	}
+
+	fmt.Printf("\nCreated %d, updated %d, unchanged %d, failed %d\n", created, updated, unchanged, failed)
+	if failed > 0 {
+		log.Fatal().Int("failed", failed).Msg("some rows could not be imported")
+	}
+}
@@ -0,0 +1,64 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseRow(t *testing.T) {
+	article := &db.Article{
+		Id:                         primitive.NewObjectID(),
+		Title:                      "Body found in lake",
+		Url:                        "https://www.cnn.com/2025/02/26/world/video/a",
+		Provider:                   "cnn",
+		AiSuggestsDownloadingVideo: true,
+		VictimNames:                []string{"Jane Doe", "John Doe"},
+		CaseId:                     7,
+		RelevantTimestamps:         []db.RelevantTimestamp{{Start: "00:25", End: "00:28", TextSnippet: "found near the shore"}},
+	}
+
+	// an exported row comes back unchanged
+	row := articleRow(article)
+	parsed, err := parseRow(row)
+	require.NoError(t, err)
+	require.Equal(t, article.Id, parsed.Id)
+	require.Empty(t, changedFields(article, parsed, true))
+
+	// corrections made in a spreadsheet
+	row[10] = "Jane Doe,  Janet Doe"
+	row[11] = "Lake Tahoe"
+	row[12] = "8"
+	row[13] = ""
+	parsed, err = parseRow(row)
+	require.NoError(t, err)
+	require.Equal(t, bson.M{
+		"victimNames": []string{"Jane Doe", "Janet Doe"},
+		"location":    "Lake Tahoe",
+		"caseId":      int32(8),
+	}, changedFields(article, parsed, false))
+	require.Equal(t, []db.RelevantTimestamp(nil), changedFields(article, parsed, true)["relevantTimestamps"])
+
+	row[7] = "TRUE"
+	row[12] = "eight"
+	_, err = parseRow(row)
+	require.ErrorContains(t, err, "Case ID")
+
+	row[12] = "8"
+	row[0] = "not an id"
+	_, err = parseRow(row)
+	require.ErrorContains(t, err, "invalid ID")
+}
+
+func TestReadRows(t *testing.T) {
+	rows, err := readRows(strings.NewReader("\ufeff" + strings.Join(header, ",") + "\n" + strings.Repeat("x,", len(header)-1) + "x\n"))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	_, err = readRows(strings.NewReader("ID,Title,URL\n"))
+	require.ErrorContains(t, err, "expected the columns")
+}
@@ -0,0 +1,188 @@
+package custody
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	use   = "custody"
+	short = "Verify the chain of custody of the downloaded videos"
+	long  = `Every video download-videos saves is recorded in the custody log: the page it was found on, the url
+it was downloaded from, the headers of the response, its size, its SHA-256 and when it was captured.
+Videos deleted by article delete are recorded as disposed, with their hash right before. Every entry
+contains the hash of the entry before it, so that the log cannot be changed without breaking the chain.`
+
+	// Command flags
+	skipFiles  bool
+	jsonFormat bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+	Long:  long,
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the chain of the custody log and that every video still has the recorded hash",
+	Long: `Check that the entries of the custody log form an unbroken chain, and hash every recorded file again
+to check that it has not changed since it was captured. A file that was downloaded again is checked
+against its last capture, and a file deleted by article delete is recorded as disposed instead of
+reported as missing. Exits with an error if anything does not match.
+
+The hash of the last entry is printed: keeping it outside of the database shows later that the log
+was not rewritten as a whole.`,
+	Args: cobra.NoArgs,
+	Run:  runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(&skipFiles, "skip-files", false, "Only check the chain, without hashing the files")
+	verifyCmd.Flags().BoolVarP(&jsonFormat, "json", "j", false, "Output in JSON format")
+	Cmd.AddCommand(verifyCmd)
+}
+
+// Report is the outcome of verifying the custody log.
+type Report struct {
+	Entries  int                 `json:"entries"`
+	Files    int                 `json:"files"`
+	Disposed int                 `json:"disposed"` // Files deleted on purpose, e.g. by article delete
+	HeadHash string              `json:"headHash"` // Hash of the last entry of the chain
+	Problems []db.CustodyProblem `json:"problems"`
+	// Videos that were downloaded before their capture was recorded, which cannot be verified
+	Unrecorded int `json:"unrecorded"`
+}
+
+// verifyFiles hashes the file of the last capture of every path again and compares it with the capture.
+// A path whose last entry is a disposal is not checked, as the file was deleted on purpose, but the
+// disposal must have the hash of the capture before it. It returns the number of files it checked
+// and of files that were disposed.
+func verifyFiles(entries []*db.CustodyEntry) (int, int, []db.CustodyProblem) {
+	last := map[string]*db.CustodyEntry{}
+	var problems []db.CustodyProblem
+	var paths []string
+	for _, entry := range entries {
+		previous, found := last[entry.Path]
+		if !found {
+			paths = append(paths, entry.Path)
+		}
+		if entry.Disposed() && found && !previous.Disposed() && (entry.SHA256 != previous.SHA256 || entry.Bytes != previous.Bytes) {
+			problems = append(problems, db.CustodyProblem{Sequence: entry.Sequence, Problem: fmt.Sprintf("%s changed before it was disposed", entry.Path)})
+		}
+		last[entry.Path] = entry
+	}
+
+	files, disposed := 0, 0
+	for _, path := range paths {
+		entry := last[path]
+		if entry.Disposed() {
+			disposed++
+			continue
+		}
+		files++
+		sum, size, err := db.HashFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, db.CustodyProblem{Sequence: entry.Sequence, Problem: fmt.Sprintf("%s is missing", path)})
+		case err != nil:
+			problems = append(problems, db.CustodyProblem{Sequence: entry.Sequence, Problem: fmt.Sprintf("%s cannot be read: %v", path, err)})
+		case sum != entry.SHA256 || size != entry.Bytes:
+			problems = append(problems, db.CustodyProblem{Sequence: entry.Sequence, Problem: fmt.Sprintf("%s changed: it has %d bytes with SHA-256 %s, %d bytes with SHA-256 %s were captured", path, size, sum, entry.Bytes, entry.SHA256)})
+		}
+	}
+	return files, disposed, problems
+}
+
+// countUnrecorded counts the downloaded videos of articles that have no capture in the custody log.
+func countUnrecorded(cmd *cobra.Command, entries []*db.CustodyEntry) int {
+	recorded := map[string]bool{}
+	for _, entry := range entries {
+		recorded[entry.Path] = true
+	}
+	unrecorded := 0
+	filter := bson.M{"videoPath": bson.M{"$nin": bson.A{nil, ""}}}
+	for article, err := range db.Models.ScanArticles(cmd.Context(), filter, db.ScanOptions{Projection: bson.M{"videoPath": 1}}) {
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to find downloaded videos")
+		}
+		if recorded[article.VideoPath] {
+			continue
+		}
+		if _, err := os.Stat(article.VideoPath); err == nil {
+			unrecorded++
+		}
+	}
+	return unrecorded
+}
+
+func runVerify(cmd *cobra.Command, args []string) {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+
+	entries, err := db.Models.FindCustodyEntries(cmd.Context())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to read the custody log")
+	}
+
+	report := Report{Entries: len(entries), Problems: db.VerifyCustodyChain(entries)}
+	if len(entries) > 0 {
+		report.HeadHash = entries[len(entries)-1].Hash
+	}
+	if !skipFiles {
+		files, disposed, problems := verifyFiles(entries)
+		report.Files = files
+		report.Disposed = disposed
+		report.Problems = append(report.Problems, problems...)
+		report.Unrecorded = countUnrecorded(cmd, entries)
+	}
+
+	if jsonFormat {
+		if report.Problems == nil {
+			report.Problems = []db.CustodyProblem{}
+		}
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to marshal to JSON")
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		if len(report.Problems) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SEQUENCE\tPROBLEM")
+			for _, problem := range report.Problems {
+				fmt.Fprintf(w, "%d\t%s\n", problem.Sequence, problem.Problem)
+			}
+			w.Flush()
+			fmt.Println()
+		}
+		fmt.Printf("Entries:  %d\n", report.Entries)
+		if !skipFiles {
+			fmt.Printf("Files:    %d\n", report.Files)
+			fmt.Printf("Disposed: %d\n", report.Disposed)
+		}
+		if report.HeadHash != "" {
+			fmt.Printf("Head:     %s\n", report.HeadHash)
+		}
+		if report.Unrecorded > 0 {
+			fmt.Printf("\n%d downloaded videos were captured before custody was recorded and cannot be verified\n", report.Unrecorded)
+		}
+	}
+
+	if len(report.Problems) > 0 {
+		log.Fatal().Int("problems", len(report.Problems)).Msg("the chain of custody is broken")
+	}
+	log.Info().Msg("the chain of custody is intact")
+}
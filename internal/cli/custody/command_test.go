@@ -0,0 +1,45 @@
+package custody
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyFiles(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept.mp4")
+	require.NoError(t, os.WriteFile(kept, []byte("kept video"), 0o644))
+	deleted := filepath.Join(dir, "deleted.mp4")
+	require.NoError(t, os.WriteFile(deleted, []byte("deleted video"), 0o644))
+
+	capture := func(sequence int64, event db.CustodyEvent, path string) *db.CustodyEntry {
+		sum, size, err := db.HashFile(path)
+		require.NoError(t, err)
+		return &db.CustodyEntry{Sequence: sequence, Event: event, Path: path, Bytes: size, SHA256: sum}
+	}
+	entries := []*db.CustodyEntry{
+		capture(1, db.CustodyCaptured, kept),
+		capture(2, db.CustodyCaptured, deleted),
+		capture(3, db.CustodyDisposed, deleted),
+	}
+	require.NoError(t, os.Remove(deleted))
+
+	// a disposed file is not missing
+	files, disposed, problems := verifyFiles(entries)
+	require.Equal(t, 1, files)
+	require.Equal(t, 1, disposed)
+	require.Empty(t, problems)
+
+	// a file that was changed before its disposal is reported
+	entries[2].SHA256 = "changed"
+	_, _, problems = verifyFiles(entries)
+	require.Equal(t, []db.CustodyProblem{{Sequence: 3, Problem: deleted + " changed before it was disposed"}}, problems)
+
+	// without the disposal, the deleted file is missing
+	_, _, problems = verifyFiles(entries[:2])
+	require.Equal(t, []db.CustodyProblem{{Sequence: 2, Problem: deleted + " is missing"}}, problems)
+}
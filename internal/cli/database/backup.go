@@ -0,0 +1,88 @@
+package database
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Command flags
+	jsonl      bool
+	outputFile string
+	mode       string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Back up the articles and queries as JSON lines",
+	Long: `Write every article and query to standard out, or to the file given with -o, one document per line
+as MongoDB Extended JSON. Unlike the CSV export, the backup keeps every stored field, including the ones
+the clustering pipeline adds, with their ObjectIDs and types, so that db import restores them exactly.`,
+	Args: cobra.NoArgs,
+	Run:  runExport,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Restore the articles and queries of a backup made by db export",
+	Long: `Restore the articles and queries of a backup made by db export. In merge mode, the stored documents
+are kept and only the documents whose ID, or url for articles, is not stored yet are imported. In replace mode,
+every stored article and query is deleted first. The backup is checked before anything is changed.
+
+Backups made by an older version may need db migrate after importing them.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runImport,
+}
+
+func init() {
+	exportCmd.Flags().BoolVar(&jsonl, "jsonl", true, "Export as JSON lines, the only format db export supports")
+	exportCmd.Flags().StringVarP(&outputFile, "output", "o", "", "File to write the backup to instead of standard out")
+	importCmd.Flags().StringVarP(&mode, "mode", "m", string(db.RestoreMerge), "What to do with the stored documents: merge or replace")
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	if !jsonl {
+		log.Fatal().Msg("db export only supports JSON lines, use the csv command for CSV")
+	}
+	connect()
+
+	out := os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			log.Fatal().Err(err).Str("file", outputFile).Msg("failed to create file")
+		}
+		defer file.Close()
+		out = file
+	}
+
+	counts, err := db.ExportBackup(cmd.Context(), db.Models, out)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to export database")
+	}
+	for _, collection := range db.BackupCollections {
+		log.Info().Str("collection", collection).Int64("documents", counts[collection]).Msg("exported")
+	}
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	file, err := os.Open(args[0])
+	if err != nil {
+		log.Fatal().Err(err).Str("file", args[0]).Msg("failed to open backup")
+	}
+	defer file.Close()
+	connect()
+
+	counts, err := db.ImportBackup(cmd.Context(), db.Models, file, db.RestoreMode(mode))
+	for _, collection := range db.BackupCollections {
+		count := counts[collection]
+		fmt.Printf("Imported %d %s, skipped %d that were already stored\n", count.Imported, collection, count.Skipped)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to import backup")
+	}
+}
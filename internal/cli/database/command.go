@@ -0,0 +1,102 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	use   = "db"
+	short = "Manage the database schema and backups"
+	long  = `Articles record the version of the schema they follow in schemaVersion. Migrations bring articles
+stored with an older schema, or written by other tools, up to the current version. They are applied
+in order and recorded in the migrations collection, so each one runs only once.`
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+	Long:  long,
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply every pending migration",
+	Run:   runMigrate,
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which migrations have been applied and which are pending",
+	Run:   runStatus,
+}
+
+func init() {
+	Cmd.AddCommand(migrateCmd)
+	Cmd.AddCommand(statusCmd)
+	Cmd.AddCommand(exportCmd)
+	Cmd.AddCommand(importCmd)
+}
+
+func connect() {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+}
+
+func runMigrate(cmd *cobra.Command, args []string) {
+	connect()
+	applied, err := db.Migrate(cmd.Context(), db.Models)
+	for _, record := range applied {
+		fmt.Printf("Applied %d %s, changed %d articles\n", record.Version, record.Name, record.Changed)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to migrate database")
+	}
+	if len(applied) == 0 {
+		fmt.Println("The database is up to date")
+	}
+}
+
+func runStatus(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	connect()
+	records, err := db.Models.FindMigrations(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to find applied migrations")
+	}
+	appliedAt := make(map[int]time.Time, len(records))
+	for _, record := range records {
+		appliedAt[record.Version] = record.AppliedAt
+	}
+
+	pending := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tDESCRIPTION")
+	for _, m := range db.Migrations {
+		status := "pending"
+		if at, found := appliedAt[m.Version]; found {
+			status = "applied " + at.Local().Format(time.DateTime)
+		} else {
+			pending++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.Version, m.Name, status, m.Description)
+	}
+	w.Flush()
+
+	current := db.CurrentSchemaVersion()
+	outdated, err := db.CountArticlesBelowSchemaVersion(ctx, db.Models, current)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to count outdated articles")
+	}
+	fmt.Printf("\nSchema version: %d\n", current)
+	fmt.Printf("Pending migrations: %d\n", pending)
+	fmt.Printf("Articles below schema version %d: %d\n", current, outdated)
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/profile"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,8 +16,9 @@ var (
 	short = "Analyzes articles to determine the location where bodies were found"
 
 	// Command flags
-	modelName string
-	limit     int
+	modelName         string
+	limit             int
+	recomputeOutdated bool
 )
 
 var Cmd = &cobra.Command{
@@ -29,37 +31,55 @@ func init() {
 	// Add flags to the command
 	Cmd.Flags().StringVarP(&modelName, "model", "m", "llama3.1", "Model to use for determining location")
 	Cmd.Flags().IntVarP(&limit, "limit", "l", 0, "Limit the number of articles to process (0 means no limit)")
+	Cmd.Flags().BoolVar(&recomputeOutdated, "recompute-outdated", false, "Determine again the locations derived by another model or prompt, or without provenance")
 }
 
 func run(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 
 	// Find articles where location field is not set
 	filter := bson.M{"location": bson.M{"$exists": false}}
-	articles, err := db.Models.FindArticlesByFilter(ctx, filter)
+	if recomputeOutdated {
+		// only values that were derived can be outdated
+		filter = bson.M{"location": bson.M{"$nin": bson.A{nil, ""}}}
+	}
+	// values set by hand and locked are never overwritten
+	db.ExcludeLocked(filter, "location")
+	count, err := db.Models.CountArticles(ctx, filter)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to find articles without location")
+		log.Fatal().Err(err).Msg("failed to count articles without location")
 	}
 
-	if len(articles) == 0 {
+	if count == 0 {
 		log.Info().Msg("no articles found without location information")
 		return
 	}
 
-	count := len(articles)
-	if limit > 0 && limit < count {
-		count = limit
-		articles = articles[:limit]
+	if limit > 0 && int64(limit) < count {
+		count = int64(limit)
 	}
 
-	log.Info().Int("count", count).Msg("found articles that need location determination")
+	log.Info().Int64("count", count).Msg("found articles that need location determination")
 
-	for i, article := range articles {
-		log.Info().Int("current", i+1).Int("total", count).Str("url", article.Url).Msg("determining location")
+	// Stream the articles in small batches, every article waits for the model
+	articles := db.Models.ScanArticles(ctx, filter, db.ScanOptions{BatchSize: 20, Projection: db.WithoutEmbeddings})
+	i := int64(0)
+	for article, err := range articles {
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to read articles without location")
+		}
+		if i == count {
+			break
+		}
+		if recomputeOutdated && article.DerivedBy("location", modelName, profile.Active.Prompts.Location) {
+			continue
+		}
+		i++
+		log.Info().Int64("current", i).Int64("total", count).Str("url", article.Url).Msg("determining location")
 
 		// Combine all available data about the article to help determine the location
 		fullPrompt := fmt.Sprintf("Title: %s\nDescription: %s\nDate: %s\nProvider: %s\n",
@@ -69,23 +89,33 @@ func run(cmd *cobra.Command, args []string) {
 			article.Provider)
 
 		// Determine the location using Ollama LLM
-		location, err := determineLocation(fullPrompt)
+		location, response, err := determineLocation(fullPrompt)
 		if err != nil {
 			log.Err(err).Str("url", article.Url).Msg("failed to determine location, skipping")
+			db.FinishStage(ctx, article.Id, db.StageEnriched, err)
 			continue
 		}
 
 		// Update the article with the determined location
 		update := bson.M{
-			"location": location,
+			"location":                    location,
+			db.ProvenancePath("location"): db.NewFieldProvenance(modelName, profile.Active.Prompts.Location, response),
 		}
 
 		err = db.Models.UpdateArticle(ctx, article.Id, update)
 		if err != nil {
 			log.Err(err).Str("url", article.Url).Msg("failed to update article with location")
+			db.FinishStage(ctx, article.Id, db.StageEnriched, err)
 			continue
 		}
 
+		// the article is enriched once both the victim names and the location are determined
+		if len(article.VictimNames) > 0 {
+			db.FinishStage(ctx, article.Id, db.StageEnriched, nil)
+		} else {
+			db.StartStage(ctx, article.Id, db.StageEnriched)
+		}
+
 		log.Info().Str("url", article.Url).Str("location", location).Msg("updated article with location")
 	}
 
@@ -93,22 +123,16 @@ func run(cmd *cobra.Command, args []string) {
 }
 
 // determineLocation calls the Ollama API to determine the location from the article data
-func determineLocation(articleData string) (string, error) {
-	systemPrompt := `You are an AI helping to identify locations in news articles about missing persons and bodies found.
-Based on the article information provided, determine the location where the body was found or the incident occurred.
-Return ONLY the location name with no explanations or additional text.
-Be as specific as possible, including city, state, country, or other geographical indicators if available.
-If you cannot determine a location, respond with "Unknown".
-Format your response as a simple location string, for example: "Denver, Colorado" or "Lake Michigan near Chicago".
-`
+// It also returns the raw response of the model.
+func determineLocation(articleData string) (string, string, error) {
 	// Call local Ollama API
-	response, err := CallOllama(modelName, systemPrompt, articleData)
+	response, err := CallOllama(modelName, profile.Active.Prompts.Location.Text, articleData)
 	if err != nil {
-		return "", fmt.Errorf("failed to call Ollama: %w", err)
+		return "", "", fmt.Errorf("failed to call Ollama: %w", err)
 	}
 
 	// Clean up the response
-	return cleanResponse(response), nil
+	return cleanResponse(response), response, nil
 }
 
 // cleanResponse cleans up the LLM response to ensure it's a usable format
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/profile"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,8 +16,9 @@ var (
 	short = "Analyzes articles to determine the victim's name"
 
 	// Command flags
-	modelName string
-	limit     int
+	modelName         string
+	limit             int
+	recomputeOutdated bool
 )
 
 var Cmd = &cobra.Command{
@@ -29,38 +31,56 @@ func init() {
 	// Add flags to the command
 	Cmd.Flags().StringVarP(&modelName, "model", "m", "llama3.1", "Model to use for determining victim name")
 	Cmd.Flags().IntVarP(&limit, "limit", "l", 0, "Limit the number of articles to process (0 means no limit)")
+	Cmd.Flags().BoolVar(&recomputeOutdated, "recompute-outdated", false, "Determine again the victim names derived by another model or prompt, or without provenance")
 }
 
 func run(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 
 	// Find articles where victim names array is not set
 	// The query looks for articles where victimNames field doesn't exist
 	filter := bson.M{"victimNames": bson.M{"$exists": false}}
-	articles, err := db.Models.FindArticlesByFilter(ctx, filter)
+	if recomputeOutdated {
+		// only values that were derived can be outdated
+		filter = bson.M{"victimNames": bson.M{"$nin": bson.A{nil, ""}}}
+	}
+	// values set by hand and locked are never overwritten
+	db.ExcludeLocked(filter, "victimNames")
+	count, err := db.Models.CountArticles(ctx, filter)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to find articles without victim names")
+		log.Fatal().Err(err).Msg("failed to count articles without victim names")
 	}
 
-	if len(articles) == 0 {
+	if count == 0 {
 		log.Info().Msg("no articles found without victim names")
 		return
 	}
 
-	count := len(articles)
-	if limit > 0 && limit < count {
-		count = limit
-		articles = articles[:limit]
+	if limit > 0 && int64(limit) < count {
+		count = int64(limit)
 	}
 
-	log.Info().Int("count", count).Msg("found articles that need victim name determination")
+	log.Info().Int64("count", count).Msg("found articles that need victim name determination")
 
-	for i, article := range articles {
-		log.Info().Int("current", i+1).Int("total", count).Str("url", article.Url).Msg("determining victim names")
+	// Stream the articles in small batches, every article waits for the model
+	articles := db.Models.ScanArticles(ctx, filter, db.ScanOptions{BatchSize: 20, Projection: db.WithoutEmbeddings})
+	i := int64(0)
+	for article, err := range articles {
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to read articles without victim names")
+		}
+		if i == count {
+			break
+		}
+		if recomputeOutdated && article.DerivedBy("victimNames", modelName, profile.Active.Prompts.Victim) {
+			continue
+		}
+		i++
+		log.Info().Int64("current", i).Int64("total", count).Str("url", article.Url).Msg("determining victim names")
 
 		// Combine all available data about the article to help determine the victims
 		fullPrompt := fmt.Sprintf("Title: %s\nDescription: %s\nDate: %s\nProvider: %s\n",
@@ -70,23 +90,33 @@ func run(cmd *cobra.Command, args []string) {
 			article.Provider)
 
 		// Determine the victim names using Ollama LLM
-		victimNames, err := determineVictimNames(fullPrompt)
+		victimNames, response, err := determineVictimNames(fullPrompt)
 		if err != nil {
 			log.Err(err).Str("url", article.Url).Msg("failed to determine victim names, skipping")
+			db.FinishStage(ctx, article.Id, db.StageEnriched, err)
 			continue
 		}
 
 		// Update the article with the determined victim names
 		update := bson.M{
-			"victimNames": victimNames,
+			"victimNames":                    victimNames,
+			db.ProvenancePath("victimNames"): db.NewFieldProvenance(modelName, profile.Active.Prompts.Victim, response),
 		}
 
 		err = db.Models.UpdateArticle(ctx, article.Id, update)
 		if err != nil {
 			log.Err(err).Str("url", article.Url).Msg("failed to update article with victim names")
+			db.FinishStage(ctx, article.Id, db.StageEnriched, err)
 			continue
 		}
 
+		// the article is enriched once both the victim names and the location are determined
+		if article.Location != "" {
+			db.FinishStage(ctx, article.Id, db.StageEnriched, nil)
+		} else {
+			db.StartStage(ctx, article.Id, db.StageEnriched)
+		}
+
 		log.Info().Str("url", article.Url).Strs("victimNames", victimNames).Msg("updated article with victim names")
 	}
 
@@ -94,23 +124,16 @@ func run(cmd *cobra.Command, args []string) {
 }
 
 // determineVictimNames calls the Ollama API to determine the victim names from the article data
-func determineVictimNames(articleData string) ([]string, error) {
-	systemPrompt := `You are an AI helping to identify victims in news articles about missing persons and bodies found.
-Based on the article information provided, determine the name(s) of the victim(s).
-If multiple victims are mentioned, return all of their names separated by semicolons (;).
-Return ONLY the victim names with no explanations or additional text.
-If you cannot determine any victim's name, respond with "Unknown".
-Do not include titles (Mr., Mrs., Dr., etc.) unless they are part of a formal name like "Dr. Martin Luther King Jr.".
-Format your response as: "Name1; Name2; Name3" if multiple victims are present.
-`
+// It also returns the raw response of the model.
+func determineVictimNames(articleData string) ([]string, string, error) {
 	// Call local Ollama API
-	response, err := CallOllama(modelName, systemPrompt, articleData)
+	response, err := CallOllama(modelName, profile.Active.Prompts.Victim.Text, articleData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Ollama: %w", err)
+		return nil, "", fmt.Errorf("failed to call Ollama: %w", err)
 	}
 
 	// Clean up the response and split into multiple names if needed
-	return parseVictimNames(response), nil
+	return parseVictimNames(response), response, nil
 }
 
 // parseVictimNames processes the LLM response and returns a list of victim names
@@ -2,18 +2,24 @@ package download_videos
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chromedp/chromedp"
 	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/debugartifacts"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers/cnn"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/bson"
@@ -26,6 +32,11 @@ var (
 	downloadDir = "./downloads"
 	// Number of concurrent downloads
 	concurrentDownloads = 5
+
+	// Command flags
+	debugArtifactsDir string
+	// Directory of this run's debug artifacts, inside debugArtifactsDir
+	runDebugDir string
 )
 
 var Cmd = &cobra.Command{
@@ -35,6 +46,8 @@ var Cmd = &cobra.Command{
 }
 
 func init() {
+	Cmd.Flags().StringVar(&debugArtifactsDir, "debug-artifacts", "", "Save a screenshot, the rendered HTML, the console log and the last network requests of failed page loads to this directory")
+
 	// Create downloads directory if it doesn't exist
 	if err := os.MkdirAll(downloadDir, 0700); err != nil {
 		log.Fatal().Err(err).Msg("failed to create downloads directory")
@@ -43,8 +56,11 @@ func init() {
 
 func run(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
+	if debugArtifactsDir != "" {
+		runDebugDir = filepath.Join(debugArtifactsDir, "download-videos-"+time.Now().UTC().Format("20060102T150405Z"))
+	}
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 
@@ -53,39 +69,51 @@ func run(cmd *cobra.Command, args []string) {
 		"aiSuggestsDownloadingVideo": true,
 	}
 
-	articles, err := db.Models.FindArticlesByFilter(ctx, filter)
+	count, err := db.Models.CountArticles(ctx, filter)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to find articles")
+		log.Fatal().Err(err).Msg("failed to count articles")
 	}
-
-	if len(articles) == 0 {
+	if count == 0 {
 		log.Info().Msg("no videos to download")
 		return
 	}
 
-	log.Info().Int("count", len(articles)).Msg("found videos to download")
-
-	if len(articles) == 0 {
-		log.Info().Msg("no videos to download")
-		return
+	log.Info().Int64("count", count).Msg("found videos to download")
+	// Stream the articles in small batches, every download takes a while
+	scan := db.Models.ScanArticles(ctx, filter, db.ScanOptions{BatchSize: 20, Projection: db.WithoutEmbeddings})
+	articles := func(yield func(*db.Article) bool) {
+		for article, err := range scan {
+			if err != nil {
+				log.Fatal().Err(err).Msg("failed to find articles")
+			}
+			if !yield(article) {
+				return
+			}
+		}
 	}
+	DownloadArticles(ctx, articles)
+	log.Info().Msg("all processing completed")
+}
 
+// DownloadArticles determines the video URL of every article and downloads the videos that are not on disk yet.
+// It returns the number of videos that were downloaded.
+func DownloadArticles(ctx context.Context, articles iter.Seq[*db.Article]) int {
 	// Create a wait group to wait for all processing to complete
 	var wg sync.WaitGroup
 	// Create a semaphore to limit concurrent processing
 	sem := make(chan struct{}, concurrentDownloads)
+	var downloaded atomic.Int32
 
 	// Process videos in parallel (URL determination AND downloading)
-	for _, article := range articles {
+	for article := range articles {
+		// Acquire semaphore before starting, so that articles are only read as they can be processed
+		sem <- struct{}{}
 		// Add to wait group before starting goroutine
 		wg.Add(1)
 
 		// Start a goroutine for each article
 		go func(article *db.Article) {
 			defer wg.Done()
-
-			// Acquire semaphore
-			sem <- struct{}{}
 			defer func() { <-sem }() // Release semaphore when done
 
 			log.Info().Str("url", article.Url).Str("title", article.Title).Msg("processing video")
@@ -99,17 +127,23 @@ func run(cmd *cobra.Command, args []string) {
 			// Skip articles that already have been downloaded
 			if _, err := os.Stat(article.VideoPath); err == nil {
 				log.Info().Str("url", article.Url).Str("path", article.VideoPath).Msg("video already downloaded, skipping")
+				if article.Stages[db.StageDownloaded].State != db.StageDone {
+					db.FinishStage(ctx, article.Id, db.StageDownloaded, nil)
+				}
 				return
 			}
 
 			switch article.Provider {
 			case "cnn":
 				// Download the video
-				videoPath, err := downloadVideo(article, article.VideoUrl)
+				db.StartStage(ctx, article.Id, db.StageDownloaded)
+				videoPath, err := downloadVideo(ctx, article, article.VideoUrl)
+				db.FinishStage(ctx, article.Id, db.StageDownloaded, err)
 				if err != nil {
 					log.Err(err).Str("url", article.Url).Msg("failed to download video")
 					return
 				}
+				downloaded.Add(1)
 				log.Info().Str("url", article.Url).Str("path", videoPath).Msg("successfully downloaded video")
 			default:
 				log.Warn().Str("provider", article.Provider).Msg("unsupported provider for video download")
@@ -120,7 +154,7 @@ func run(cmd *cobra.Command, args []string) {
 
 	// Wait for all processing to complete
 	wg.Wait()
-	log.Info().Msg("all processing completed")
+	return int(downloaded.Load())
 }
 
 // getVideoFilePath returns the path where the video file for an article should be stored
@@ -151,9 +185,12 @@ func determineVideoUrl(ctx context.Context, article *db.Article) error {
 	case "cnn":
 		videoUrl, err = determineCnnVideoUrl(ctx, article)
 	default:
-		return fmt.Errorf("unsupported provider: %s", article.Provider)
+		err = fmt.Errorf("unsupported provider: %s", article.Provider)
+		db.FinishStage(ctx, article.Id, db.StageResolved, err)
+		return err
 	}
 	if err != nil {
+		db.FinishStage(ctx, article.Id, db.StageResolved, err)
 		return fmt.Errorf("failed to determine video URL: %w", err)
 	}
 	// extract extension from the URL
@@ -168,8 +205,10 @@ func determineVideoUrl(ctx context.Context, article *db.Article) error {
 		"videoPath": videoPath,
 	}
 	if err := db.Models.UpdateArticle(ctx, article.Id, update); err != nil {
+		db.FinishStage(ctx, article.Id, db.StageResolved, err)
 		return fmt.Errorf("failed to update article with video URL: %w", err)
 	}
+	db.FinishStage(ctx, article.Id, db.StageResolved, nil)
 	// update local article object
 	article.VideoUrl = videoUrl
 	article.VideoPath = videoPath
@@ -204,25 +243,29 @@ func determineCnnVideoUrl(ctx context.Context, article *db.Article) (string, err
 		}),
 	}
 
-	chromectx, cancel := chromedp.NewContext(allocCtx, logOpts...)
+	tabCtx, cancel := chromedp.NewContext(allocCtx, logOpts...)
 	defer cancel()
+	recorder := debugartifacts.Listen(tabCtx, runDebugDir)
 
 	// Set a timeout
-	chromectx, cancel = context.WithTimeout(chromectx, 60*time.Second)
+	chromectx, cancel := context.WithTimeout(tabCtx, 60*time.Second)
 	defer cancel()
 
 	log.Info().Str("url", article.Url).Msg("navigating to article URL with ChromeDP")
 
 	var videoUri string
+	setup := cnn.DefaultBrowserSetup()
 	// Navigate to the page and extract the video URI using JavaScript
 	err := chromedp.Run(chromectx,
+		setup.Prepare(),
 		chromedp.Navigate(article.Url),
-		// Wait for the video element to be present
-		chromedp.WaitVisible(`div[data-video-id]`, chromedp.ByQuery),
+		// Wait for the video element to be present, dismissing consent banners in the way
+		setup.WaitReady(`div[data-video-id]`),
 		// Execute JavaScript to get the URI
 		chromedp.Evaluate(`document.querySelector("div[data-video-id]").dataset.uri`, &videoUri),
 	)
 	if err != nil {
+		recorder.Save(tabCtx, "video page "+article.Id.Hex(), err)
 		return "", fmt.Errorf("failed to extract video URI using ChromeDP: %w", err)
 	}
 
@@ -268,9 +311,9 @@ func determineCnnVideoUrl(ctx context.Context, article *db.Article) (string, err
 	return videoURL, nil
 }
 
-// downloadVideo downloads a video from the provided URL and saves it to the filesystem
-// Returns the path to the downloaded video file
-func downloadVideo(article *db.Article, videoURL string) (string, error) {
+// downloadVideo downloads a video from the provided URL and saves it to the filesystem, and records
+// the capture in the custody log. Returns the path to the downloaded video file
+func downloadVideo(ctx context.Context, article *db.Article, videoURL string) (string, error) {
 	// Extract file extension from the URL
 	fileExt := filepath.Ext(videoURL)
 	if fileExt == "" {
@@ -282,11 +325,16 @@ func downloadVideo(article *db.Article, videoURL string) (string, error) {
 	log.Info().Str("path", fullPath).Msg("downloading video to file")
 
 	// Download the video file
+	capturedAt := time.Now()
 	videoFileResp, err := http.Get(videoURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to download video file: %w", err)
 	}
 	defer videoFileResp.Body.Close()
+	// an error page must never be recorded as the captured media
+	if videoFileResp.StatusCode < 200 || videoFileResp.StatusCode > 299 {
+		return "", fmt.Errorf("failed to download video file: unexpected status %s", videoFileResp.Status)
+	}
 
 	// Create the output file
 	outFile, err := os.Create(fullPath)
@@ -295,12 +343,35 @@ func downloadVideo(article *db.Article, videoURL string) (string, error) {
 	}
 	defer outFile.Close()
 
-	// Copy the video data to the file
-	_, err = io.Copy(outFile, videoFileResp.Body)
+	// Copy the video data to the file, hashing it on the way
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(outFile, hash), videoFileResp.Body)
 	if err != nil {
+		// a partial file must not be taken for the video
+		outFile.Close()
+		os.Remove(fullPath)
 		return "", fmt.Errorf("failed to save video file: %w", err)
 	}
 
-	log.Info().Str("path", fullPath).Msg("video downloaded successfully")
+	entry := &db.CustodyEntry{
+		Event:      db.CustodyCaptured,
+		ArticleId:  article.Id,
+		SourceUrl:  article.Url,
+		MediaUrl:   videoURL,
+		Path:       fullPath,
+		StatusCode: videoFileResp.StatusCode,
+		Headers:    videoFileResp.Header,
+		Bytes:      written,
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+		CapturedAt: capturedAt,
+	}
+	if err := db.Models.AppendCustodyEntry(ctx, entry); err != nil {
+		// a video without custody cannot be used, it is downloaded again on the next run
+		outFile.Close()
+		os.Remove(fullPath)
+		return "", fmt.Errorf("failed to record custody of video file: %w", err)
+	}
+
+	log.Info().Str("path", fullPath).Str("sha256", entry.SHA256).Int64("custodySequence", entry.Sequence).Msg("video downloaded successfully")
 	return fullPath, nil
 }
@@ -0,0 +1,178 @@
+package expandtemplate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/querytemplate"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	use   = "expand-template"
+	short = "Expand a query template into search queries"
+	long  = `Expand a query template such as "{setting} body found {state}" into one search query per
+combination of variable values, and store the queries in the queries collection.
+
+Values are given with --var name=value1,value2 or read from a file with one value per line with
+--var-file name=path. Queries that already exist are skipped. Every stored query links back to its
+template and records the values that were substituted, so their yield can be compared per variable
+with show-queries --stats --by-variable.`
+
+	// Command flags
+	template string
+	vars     []string
+	varFiles []string
+	dryRun   bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+	Long:  long,
+	Run:   run,
+}
+
+func init() {
+	Cmd.Flags().StringVarP(&template, "template", "t", "", "The query template, with variables in braces")
+	Cmd.Flags().StringArrayVar(&vars, "var", nil, "Comma separated values for a variable, as name=value1,value2. Can be repeated")
+	Cmd.Flags().StringArrayVar(&varFiles, "var-file", nil, "File with one value per line for a variable, as name=path. Can be repeated")
+	Cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the expanded queries without storing them")
+	Cmd.MarkFlagRequired("template")
+}
+
+// parseValues collects the values given for each variable by the --var and --var-file flags
+func parseValues() (map[string][]string, error) {
+	values := make(map[string][]string)
+	for _, v := range vars {
+		name, list, found := strings.Cut(v, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid --var %q, expected name=value1,value2", v)
+		}
+		values[name] = append(values[name], strings.Split(list, ",")...)
+	}
+	for _, v := range varFiles {
+		name, path, found := strings.Cut(v, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid --var-file %q, expected name=path", v)
+		}
+		lines, err := readLines(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values for %q: %w", name, err)
+		}
+		values[name] = append(values[name], lines...)
+	}
+	return values, nil
+}
+
+// readLines returns the non-empty lines of a file
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// mergeValues adds the values that are not known yet to the known values of each variable
+func mergeValues(known, added map[string][]string) map[string][]string {
+	merged := make(map[string][]string, len(known))
+	for name, list := range known {
+		merged[name] = append([]string(nil), list...)
+	}
+	for name, list := range added {
+		for _, value := range list {
+			value = strings.TrimSpace(value)
+			found := false
+			for _, existing := range merged[name] {
+				if existing == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				merged[name] = append(merged[name], value)
+			}
+		}
+	}
+	return merged
+}
+
+func run(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	values, err := parseValues()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to parse variable values")
+	}
+	expansions, err := querytemplate.Expand(template, values)
+	if err != nil {
+		log.Fatal().Err(err).Str("template", template).Msg("failed to expand template")
+	}
+	log.Info().Str("template", template).Int("count", len(expansions)).Msg("expanded template")
+
+	if dryRun {
+		for _, e := range expansions {
+			fmt.Println(e.Query)
+		}
+		return
+	}
+
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+
+	// Reuse the stored template so that every query expanded from it links to the same document
+	stored, err := db.Models.FindQueryTemplateByValue(ctx, template)
+	if err != nil {
+		stored = &db.QueryTemplate{Template: template, Variables: mergeValues(nil, values)}
+		if err := db.Models.CreateQueryTemplate(ctx, stored); err != nil {
+			log.Fatal().Err(err).Msg("failed to save query template")
+		}
+	} else {
+		stored.Variables = mergeValues(stored.Variables, values)
+		if err := db.Models.UpdateQueryTemplate(ctx, stored.Id, bson.M{"variables": stored.Variables}); err != nil {
+			log.Fatal().Err(err).Msg("failed to update query template")
+		}
+	}
+
+	created, skipped := 0, 0
+	for _, e := range expansions {
+		// Check if this query already exists to avoid duplicates
+		if existing, err := db.Models.FindQueryByValue(ctx, e.Query); err == nil && existing != nil {
+			log.Debug().Str("query", e.Query).Msg("Query already exists, skipping")
+			skipped++
+			continue
+		}
+
+		query := &db.Query{
+			Query:          e.Query,
+			Used:           false,
+			Description:    fmt.Sprintf("Expanded from template: %s", template),
+			TemplateId:     stored.Id,
+			TemplateValues: e.Values,
+		}
+		if err := db.Models.CreateQuery(ctx, query); err != nil {
+			log.Err(err).Str("query", e.Query).Msg("failed to save query")
+			continue
+		}
+		created++
+	}
+
+	log.Info().Str("templateId", stored.Id.Hex()).Int("created", created).Int("skipped", skipped).Msg("finished expanding template")
+}
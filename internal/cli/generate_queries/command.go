@@ -2,19 +2,27 @@ package generatequeries
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/profile"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 var (
 	use   = "generate-queries"
-	short = "Generates search queries for finding news articles about missing persons and bodies found"
+	short = "Generates search queries for finding news articles on the topic of the profile"
 
 	// Command flags
-	customPrompt string
-	numQueries   int
+	customPrompt        string
+	numQueries          int
+	numSeedQueries      int
+	numSeedArticles     int
+	embeddingModel      string
+	similarityThreshold float64
 )
 
 var Cmd = &cobra.Command{
@@ -27,12 +35,16 @@ func init() {
 	// Add flags to the command
 	Cmd.Flags().StringVarP(&customPrompt, "prompt", "p", "", "Custom prompt to use for query generation")
 	Cmd.Flags().IntVarP(&numQueries, "num", "n", 5, "Number of queries to generate (default 5)")
+	Cmd.Flags().IntVar(&numSeedQueries, "seed-queries", 5, "Number of highest-yield past queries to show the model (0 disables)")
+	Cmd.Flags().IntVar(&numSeedArticles, "seed-articles", 10, "Number of recent approved articles to take locations and circumstances from (0 disables)")
+	Cmd.Flags().StringVar(&embeddingModel, "embedding-model", "nomic-embed-text", "Ollama model used to compare queries by meaning (empty disables)")
+	Cmd.Flags().Float64Var(&similarityThreshold, "similarity", 0.9, "Reject queries whose embedding is at least this similar to an existing query")
 }
 
 func run(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 
@@ -42,10 +54,31 @@ func run(cmd *cobra.Command, args []string) {
 		prompt = customPrompt
 	}
 
+	// Seed the prompt with what worked before
+	feedback, err := feedbackContext(ctx, numSeedQueries, numSeedArticles)
+	if err != nil {
+		log.Err(err).Msg("failed to build feedback from past queries, generating without it")
+	}
+	description := fmt.Sprintf("Generated using prompt: %s", prompt)
+	if feedback != "" {
+		prompt += "\n\n" + feedback
+		description += " (seeded with past query yields and approved articles)"
+	}
+
+	existing, err := db.Models.FindAllQueries(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to find existing queries")
+	}
+	// one-off search terms do not keep generation from proposing a query
+	existing = slices.DeleteFunc(existing, func(q *db.Query) bool { return q.AdHoc })
+	checker := newSimilarityChecker(ctx, existing, embeddingModel, similarityThreshold)
+
 	log.Info().Msg("Generating search queries...")
 
-	for i := 0; i < numQueries; i++ {
-		log.Info().Int("query", i+1).Int("total", numQueries).Msg("Generating query")
+	// Allow some attempts to be rejected as too similar before giving up
+	saved := 0
+	for attempt := 0; saved < numQueries && attempt < numQueries*3; attempt++ {
+		log.Info().Int("query", saved+1).Int("total", numQueries).Msg("Generating query")
 
 		// Generate query using Ollama LLM
 		queryString, err := generateQuery(prompt)
@@ -54,10 +87,10 @@ func run(cmd *cobra.Command, args []string) {
 			continue
 		}
 
-		// Check if this query already exists to avoid duplicates
-		existingQuery, err := db.Models.FindQueryByValue(ctx, queryString)
-		if err == nil && existingQuery != nil {
-			log.Info().Str("query", queryString).Msg("Query already exists, skipping")
+		// Check if this query is too close to an existing one to avoid duplicates
+		similarTo, embedding := checker.check(queryString)
+		if similarTo != "" {
+			log.Info().Str("query", queryString).Str("similarTo", similarTo).Msg("Query is too similar to an existing query, skipping")
 			continue
 		}
 
@@ -65,7 +98,11 @@ func run(cmd *cobra.Command, args []string) {
 		query := &db.Query{
 			Query:       queryString,
 			Used:        false,
-			Description: fmt.Sprintf("Generated using prompt: %s", prompt),
+			Description: description,
+		}
+		if embedding != nil {
+			query.Embedding = embedding
+			query.EmbeddingModel = embeddingModel
 		}
 
 		// Save to database
@@ -75,32 +112,29 @@ func run(cmd *cobra.Command, args []string) {
 			continue
 		}
 
+		checker.add(query)
+		saved++
 		log.Info().Str("query", queryString).Msg("Successfully generated and saved query")
 	}
 }
 
-// getPrompt returns the default prompt for generating search queries
+// getPrompt returns the prompt of the profile for generating search queries, with its seeds as example formats
 func getPrompt() string {
-	return `Generate a single search query that would be effective for finding news articles about missing persons cases or cases where bodies have been found. 
-The query should be specific enough to return relevant results but general enough to capture a wide range of cases. 
-Focus on creating search terms that would help build a dataset for tracking missing persons and bodies found.
-Return only the search query string with no explanations or additional text.
-Example formats: "body found in lake", "remains discovered woods", "missing person case solved".`
+	prompt := profile.Active.Prompts.Query
+	if len(profile.Active.Seeds) == 0 {
+		return prompt
+	}
+	examples := make([]string, len(profile.Active.Seeds))
+	for i, seed := range profile.Active.Seeds {
+		examples[i] = strconv.Quote(seed)
+	}
+	return prompt + "\nExample formats: " + strings.Join(examples, ", ") + "."
 }
 
 // generateQuery calls the Ollama API to generate a search query
 func generateQuery(prompt string) (string, error) {
-	systemPrompt := `You are helping to build a dataset of news articles about missing persons and bodies found. 
-Generate only a single search query that would help find relevant news articles.
-Return ONLY the search query with no explanations, quotes, or additional text.
-Do not use any special characters or database syntax.
-Do not join multiple queries together in any way.
-Do not attempt to return more than one query at a time.
-Do not use conjunctions (and, or, +, etc.) to combine multiple queries.
-`
-
 	// Call local Ollama API
-	response, err := CallOllama("llama3.1", systemPrompt, prompt)
+	response, err := CallOllama("llama3.1", profile.Active.Prompts.QuerySystem, prompt)
 	if err != nil {
 		return "", fmt.Errorf("failed to call Ollama: %w", err)
 	}
@@ -0,0 +1,125 @@
+package generatequeries
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// stopWords are ignored when comparing queries, so "body found in the lake" and "body found lake" match
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "the": {}, "in": {}, "on": {}, "at": {}, "of": {}, "near": {}, "for": {}, "with": {},
+}
+
+// similarityChecker rejects candidate queries that are too close to the queries already stored.
+// Queries are compared by their normalized words and, when an embedding model is available,
+// by the cosine similarity of their embeddings.
+type similarityChecker struct {
+	model      string
+	threshold  float64
+	semantic   bool
+	known      []*db.Query
+	normalized map[string]string
+}
+
+func newSimilarityChecker(ctx context.Context, queries []*db.Query, model string, threshold float64) *similarityChecker {
+	c := &similarityChecker{
+		model:      model,
+		threshold:  threshold,
+		semantic:   model != "",
+		normalized: make(map[string]string, len(queries)),
+	}
+	for _, q := range queries {
+		if c.semantic && (len(q.Embedding) == 0 || q.EmbeddingModel != model) {
+			embedding, err := CallOllamaEmbedding(model, q.Query)
+			if err != nil {
+				log.Warn().Err(err).Str("model", model).Msg("embeddings unavailable, only rejecting queries with the same words")
+				c.semantic = false
+			} else {
+				q.Embedding = embedding
+				q.EmbeddingModel = model
+				update := bson.M{"embedding": embedding, "embeddingModel": model}
+				if err := db.Models.UpdateQuery(ctx, q.Id, update); err != nil {
+					log.Err(err).Str("query", q.Query).Msg("failed to store query embedding")
+				}
+			}
+		}
+		c.add(q)
+	}
+	return c
+}
+
+// add makes a query known to the checker.
+func (c *similarityChecker) add(q *db.Query) {
+	c.known = append(c.known, q)
+	c.normalized[normalizeQuery(q.Query)] = q.Query
+}
+
+// check returns the known query the candidate is too similar to, or an empty string if there is none.
+// The candidate's embedding is returned so it can be stored alongside the new query.
+func (c *similarityChecker) check(candidate string) (similarTo string, embedding []float64) {
+	if existing, found := c.normalized[normalizeQuery(candidate)]; found {
+		return existing, nil
+	}
+	if !c.semantic {
+		return "", nil
+	}
+
+	embedding, err := CallOllamaEmbedding(c.model, candidate)
+	if err != nil {
+		log.Warn().Err(err).Str("query", candidate).Msg("failed to embed query, only compared its words")
+		return "", nil
+	}
+	best, bestScore := "", 0.0
+	for _, q := range c.known {
+		if q.EmbeddingModel != c.model {
+			continue
+		}
+		if score := cosineSimilarity(embedding, q.Embedding); score > bestScore {
+			best, bestScore = q.Query, score
+		}
+	}
+	if bestScore >= c.threshold {
+		log.Debug().Str("query", candidate).Str("similarTo", best).Float64("similarity", bestScore).Msg("query is too similar")
+		return best, embedding
+	}
+	return "", embedding
+}
+
+// normalizeQuery lowercases a query, drops punctuation and stop words and sorts the remaining words.
+func normalizeQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if _, stop := stopWords[w]; !stop {
+			kept = append(kept, w)
+		}
+	}
+	sort.Strings(kept)
+	return strings.Join(kept, " ")
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or 0 if they cannot be compared.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,20 @@
+package generatequeries
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	require.Equal(t, normalizeQuery("Body found in the lake"), normalizeQuery("lake body found"))
+	require.Equal(t, "body found lake", normalizeQuery(`"Body found, lake!"`))
+	require.NotEqual(t, normalizeQuery("body found in lake"), normalizeQuery("body found in river"))
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	require.InDelta(t, 1.0, cosineSimilarity([]float64{1, 2, 3}, []float64{2, 4, 6}), 1e-9)
+	require.InDelta(t, 0.0, cosineSimilarity([]float64{1, 0}, []float64{0, 1}), 1e-9)
+	require.Equal(t, 0.0, cosineSimilarity([]float64{1, 2}, []float64{1, 2, 3}))
+	require.Equal(t, 0.0, cosineSimilarity(nil, nil))
+}
@@ -0,0 +1,87 @@
+package generatequeries
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxCircumstanceLength keeps each approved article short enough that many fit in the prompt
+const maxCircumstanceLength = 200
+
+// feedbackContext describes the past queries with the highest yield and the locations and
+// circumstances of articles approved by the AI check, to steer generation towards queries
+// that find relevant articles. It returns an empty string if there is nothing to learn from yet.
+func feedbackContext(ctx context.Context, numSeedQueries, numSeedArticles int) (string, error) {
+	var sections []string
+
+	if numSeedQueries > 0 {
+		yields, err := sleuth.QueryYields(ctx)
+		if err != nil {
+			return "", err
+		}
+		var seeds []string
+		for _, y := range yields {
+			if len(seeds) == numSeedQueries || y.Approved == 0 {
+				break
+			}
+			seeds = append(seeds, fmt.Sprintf("- %s (%d relevant articles)", y.Query, y.Approved))
+		}
+		if len(seeds) > 0 {
+			sections = append(sections, "These past queries found the most relevant articles:\n"+strings.Join(seeds, "\n"))
+		}
+	}
+
+	if numSeedArticles > 0 {
+		// the newest approved articles, reading only the fields the prompt uses
+		opts := db.ScanOptions{
+			Projection:  bson.M{"title": 1, "description": 1, "location": 1},
+			NewestFirst: true,
+			Limit:       int64(numSeedArticles),
+		}
+		var articles []*db.Article
+		for article, err := range db.Models.ScanArticles(ctx, bson.M{"aiSuggestsDownloadingVideo": true}, opts) {
+			if err != nil {
+				return "", err
+			}
+			articles = append(articles, article)
+		}
+
+		var locations, circumstances []string
+		seenLocations := make(map[string]struct{})
+		for _, article := range articles {
+			if article.Location != "" && article.Location != "Unknown" {
+				if _, found := seenLocations[article.Location]; !found {
+					seenLocations[article.Location] = struct{}{}
+					locations = append(locations, article.Location)
+				}
+			}
+			circumstance := strings.TrimSpace(article.Title + ". " + article.Description)
+			circumstances = append(circumstances, "- "+truncate(circumstance, maxCircumstanceLength))
+		}
+		if len(locations) > 0 {
+			sections = append(sections, "Relevant cases took place in: "+strings.Join(locations, "; "))
+		}
+		if len(circumstances) > 0 {
+			sections = append(sections, "Circumstances of relevant cases:\n"+strings.Join(circumstances, "\n"))
+		}
+	}
+
+	if len(sections) == 0 {
+		return "", nil
+	}
+	return strings.Join(sections, "\n\n") + "\n\nUse these to come up with a new query that would find similar cases. Do not repeat or rephrase the past queries.", nil
+}
+
+// truncate shortens s to at most n runes followed by an ellipsis, without splitting a character.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -0,0 +1,18 @@
+package generatequeries
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	require.Equal(t, "Body found", truncate("Body found", 10))
+	require.Equal(t, "Body...", truncate("Body found", 4))
+
+	// a multi-byte character at the cut is kept whole
+	truncated := truncate("Cadáver hallado en São Paulo", 5)
+	require.Equal(t, "Cadáv...", truncated)
+	require.True(t, utf8.ValidString(truncated))
+}
@@ -63,3 +63,50 @@ func CallOllama(model, systemPrompt string, prompt string) (string, error) {
 
 	return response.Response, nil
 }
+
+// Request payload for the Ollama embed API
+type OllamaEmbedRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+// Response payload from the Ollama embed API
+type OllamaEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+	Error      string      `json:"error"`
+}
+
+// CallOllamaEmbedding returns the embedding of the input text produced by the given model
+func CallOllamaEmbedding(model, input string) ([]float64, error) {
+	url := "http://localhost:11434/api/embed"
+
+	requestBody, err := json.Marshal(OllamaEmbedRequest{Model: model, Input: input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var response OllamaEmbedResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		log.Err(err).Str("response", string(body)).Msg("failed to unmarshal response")
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("ollama returned an error: %s", response.Error)
+	}
+	if len(response.Embeddings) == 0 {
+		return nil, fmt.Errorf("ollama returned no embeddings")
+	}
+
+	return response.Embeddings[0], nil
+}
@@ -28,8 +28,8 @@ var Cmd = &cobra.Command{
 
 func run(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 
@@ -81,16 +81,17 @@ func run(cmd *cobra.Command, args []string) {
 			continue
 		}
 
-		// Validate JSON before updating
-		var timestampData interface{}
-		if err := json.Unmarshal(fileBytes, &timestampData); err != nil {
-			log.Error().Str("file", filename).Err(err).Msg("invalid JSON in file")
+		// Decode the timestamps so that they are stored with the fields of the schema
+		var timestamps []db.RelevantTimestamp
+		if err := json.Unmarshal(fileBytes, &timestamps); err != nil {
+			log.Error().Str("file", filename).Err(err).Msg("invalid timestamps in file")
+			db.FinishStage(ctx, objID, db.StageTimestamped, err)
 			continue
 		}
 
 		// Update the MongoDB document
 		update := bson.M{
-			"relevantTimestamps": timestampData,
+			"relevantTimestamps": timestamps,
 		}
 		err = db.Models.UpdateArticle(ctx, objID, update)
 		if err != nil {
@@ -98,6 +99,9 @@ func run(cmd *cobra.Command, args []string) {
 			continue
 		}
 
+		// the timestamps are extracted from the transcript, so the video has been transcribed too
+		db.FinishStage(ctx, objID, db.StageTranscribed, nil)
+		db.FinishStage(ctx, objID, db.StageTimestamped, nil)
 		log.Info().Str("id", objID.Hex()).Msg("successfully updated article with timestamp metadata")
 		successCount++
 	}
@@ -0,0 +1,94 @@
+package profiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/giraffesyo/sleuth/internal/profile"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	use   = "profile"
+	short = "List and show investigation profiles"
+	long  = `A profile bundles the database, the prompts, the query seeds and the providers of an investigation,
+so that separate datasets, e.g. missing children or wildfire fatalities, are built with the same binary.
+The profile is selected with --profile or SLEUTH_PROFILE. Besides the built-in bodies-found profile,
+profiles are JSON files in ./profiles, or the directory in SLEUTH_PROFILES_DIR, named <profile>.json.
+
+Run "sleuth profile show" to print the built-in profile as a starting point for a new one. The name
+of a profile is the name of its file, its database defaults to sleuth-<profile> and its providers to
+every provider. Only the built-in profile may use the sleuth database.`
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+	Long:  long,
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles",
+	Args:  cobra.NoArgs,
+	Run:   runList,
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show [profile]",
+	Short: "Print a profile as JSON, the selected one if none is given",
+	Long: `Print a profile as JSON, the selected one if none is given. The name and database of the built-in
+profile are left out, so that its output can be saved as a new profile that takes its name from its
+file and its database from its name.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runShow,
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(showCmd)
+}
+
+func runList(cmd *cobra.Command, args []string) {
+	names, err := profile.List(profile.Dir())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to list profiles")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tDATABASE\tDESCRIPTION")
+	for _, name := range names {
+		p, err := profile.Load(profile.Dir(), name)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t-\tinvalid: %s\n", name, err)
+			continue
+		}
+		selected := ""
+		if p.Name == profile.Active.Name {
+			selected = " (selected)"
+		}
+		fmt.Fprintf(w, "%s%s\t%s\t%s\n", p.Name, selected, p.Database, p.Description)
+	}
+	w.Flush()
+}
+
+func runShow(cmd *cobra.Command, args []string) {
+	p := profile.Active
+	if len(args) == 1 {
+		var err error
+		if p, err = profile.Load(profile.Dir(), args[0]); err != nil {
+			log.Fatal().Err(err).Msg("failed to load profile")
+		}
+	}
+	if p.Name == profile.DefaultName {
+		p.Name, p.Database = "", ""
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(p); err != nil {
+		log.Fatal().Err(err).Msg("failed to encode profile")
+	}
+}
@@ -0,0 +1,135 @@
+package runs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	use   = "runs"
+	short = "Inspect the history of search runs"
+
+	// Command flags
+	limit      int64
+	jsonFormat bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the most recent search runs",
+	Run:   runList,
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show <run-id>",
+	Short: "Show every search of a run",
+	Args:  cobra.ExactArgs(1),
+	Run:   runShow,
+}
+
+func init() {
+	listCmd.Flags().Int64VarP(&limit, "limit", "l", 20, "Number of runs to list (0 means no limit)")
+	Cmd.PersistentFlags().BoolVarP(&jsonFormat, "json", "j", false, "Output in JSON format")
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(showCmd)
+}
+
+func connect() {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+}
+
+func printJSON(v any) {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to marshal to JSON")
+	}
+	fmt.Println(string(jsonData))
+}
+
+// duration formats how long a run took, or that it has not finished
+func duration(run *db.SearchRun) string {
+	if run.FinishedAt.IsZero() {
+		return "unfinished"
+	}
+	return run.FinishedAt.Sub(run.StartedAt).Round(time.Second).String()
+}
+
+func runList(cmd *cobra.Command, args []string) {
+	connect()
+	runs, err := db.Models.FindRecentRuns(cmd.Context(), limit)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to find runs")
+	}
+
+	if jsonFormat {
+		printJSON(runs)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tQUERIES\tPROVIDERS\tNEW\tDUPLICATES\tERRORS\tVERSION")
+	for _, run := range runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%d\t%d\t%s\n",
+			run.Id.Hex(), run.StartedAt.Local().Format(time.DateTime), duration(run), len(run.Queries),
+			strings.Join(run.Providers, ","), run.NewArticles, run.Duplicates, len(run.Errors), run.Version)
+	}
+	w.Flush()
+}
+
+func runShow(cmd *cobra.Command, args []string) {
+	id, err := primitive.ObjectIDFromHex(args[0])
+	if err != nil {
+		log.Fatal().Err(err).Str("id", args[0]).Msg("invalid run ID")
+	}
+
+	connect()
+	run, err := db.Models.FindRunByID(cmd.Context(), id)
+	if err != nil {
+		log.Fatal().Err(err).Str("id", args[0]).Msg("failed to find run")
+	}
+
+	if jsonFormat {
+		printJSON(run)
+		return
+	}
+
+	fmt.Printf("Run:        %s\n", run.Id.Hex())
+	fmt.Printf("Version:    %s\n", run.Version)
+	fmt.Printf("Started:    %s\n", run.StartedAt.Local().Format(time.DateTime))
+	fmt.Printf("Duration:   %s\n", duration(run))
+	fmt.Printf("Providers:  %s\n", strings.Join(run.Providers, ", "))
+	fmt.Printf("Queries:    %d\n", len(run.Queries))
+	fmt.Printf("New:        %d\n", run.NewArticles)
+	fmt.Printf("Duplicates: %d\n\n", run.Duplicates)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "QUERY\tPROVIDER\tPAGES\tNEW\tDUPLICATES\tERROR")
+	for _, s := range run.Searches {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n", s.Query, s.Provider, s.Pages, s.NewArticles, s.Duplicates, s.Error)
+	}
+	w.Flush()
+
+	if len(run.Errors) > 0 {
+		fmt.Println("\nErrors:")
+		for _, e := range run.Errors {
+			fmt.Printf("- %s\n", e)
+		}
+	}
+}
@@ -1,29 +1,54 @@
 package search
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/giraffesyo/sleuth/internal/profile"
 	"github.com/giraffesyo/sleuth/internal/sleuth"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
 	"github.com/giraffesyo/sleuth/internal/sleuth/providers/cnn"
 	"github.com/giraffesyo/sleuth/internal/sleuth/providers/fox"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var defaultProviders = []string{cnn.ProviderCNN, fox.ProviderFoxNews}
-var query string
+var queries []string
+var queriesFile string
+var fromQueries bool
+var seeds bool
 var enabledProviders []string
+var resumeRun string
+var timeout time.Duration
+var debugArtifactsDir string
 
 var (
 	use   = "search"
-	short = "Search for news articles with the provided term"
+	short = "Search for news articles with the provided terms"
 )
 
 func long() string {
 	longHelp := `
-Search for news articles with the provided term. The search term must be provided.
+Search for news articles with the provided terms. At least one search term must be provided, either
+with -q (which can be repeated), from a file with one search term per line (--queries-file), or by
+running every unused query in the queries collection (--from-queries). --seeds runs the seed
+queries of the profile, to start a new dataset.
 
-You can specify the providers to use for searching, if not provided all providers will be used.
+Each query is marked as used once it has run, and the number of new and duplicate articles it
+found is stored on the query. Search terms given with -q or --queries-file that are not stored yet
+are stored as ad hoc queries, which show-queries --stats and generate-queries leave out.
+
+The position of every provider's search is checkpointed on the run after each results page. An
+interrupted run can be continued with --resume <run-id>, which skips finished searches and picks
+up the others after their last completed page.
+
+You can specify the providers to use for searching, if not provided the providers of the profile
+will be used.
 
 Providers are:`
 
@@ -40,19 +65,84 @@ var Cmd = &cobra.Command{
 	Long:  long(),
 }
 
+// readQueriesFile returns the search terms in a file, one per line.
+// Blank lines and lines starting with # are ignored.
+func readQueriesFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	return terms, scanner.Err()
+}
+
 func run(cmd *cobra.Command, args []string) {
+	terms := queries
+	if queriesFile != "" {
+		fileTerms, err := readQueriesFile(queriesFile)
+		if err != nil {
+			log.Fatal().Err(err).Str("file", queriesFile).Msg("failed to read queries file")
+		}
+		terms = append(terms, fileTerms...)
+	}
+	if seeds {
+		terms = append(terms, profile.Active.Seeds...)
+	}
+	if !cmd.Flags().Changed("providers") {
+		enabledProviders = profile.Active.Providers
+	}
+
+	var runId primitive.ObjectID
+	if resumeRun != "" {
+		var err error
+		runId, err = primitive.ObjectIDFromHex(resumeRun)
+		if err != nil {
+			log.Fatal().Err(err).Str("run", resumeRun).Msg("invalid run id")
+		}
+	}
+
 	sleuth := sleuth.NewSleuth(
 		sleuth.WithProvider(enabledProviders...),
-		sleuth.WithSearchQuery(query),
+		sleuth.WithSearchQueries(terms...),
+		sleuth.WithUnusedQueries(fromQueries),
+		sleuth.WithResume(runId),
+		sleuth.WithTimeout(timeout),
+		sleuth.WithDebugArtifacts(debugArtifactsDir),
 	)
-	err := sleuth.Run()
+	summary, err := sleuth.Run(cmd.Context())
 	if err != nil {
 		log.Err(err).Msg("failed to run sleuth")
 	}
+	if summary != nil {
+		summary.Write(os.Stdout)
+	}
+	if err != nil || (summary != nil && summary.Failed()) {
+		os.Exit(1)
+	}
 }
 
 func init() {
-	Cmd.Flags().StringVarP(&query, "query", "q", "", "The search terms to use, wrap multiple words in quotes")
-	Cmd.Flags().StringSliceVarP(&enabledProviders, "providers", "p", defaultProviders, "The providers to use for searching, if not provided all providers will be used")
-	Cmd.MarkFlagRequired("query")
+	Cmd.Flags().StringArrayVarP(&queries, "query", "q", nil, "The search terms to use, wrap multiple words in quotes. Can be repeated")
+	Cmd.Flags().StringVar(&queriesFile, "queries-file", "", "File with one search term per line")
+	Cmd.Flags().BoolVar(&fromQueries, "from-queries", false, "Run every unused query from the queries collection")
+	Cmd.Flags().StringSliceVarP(&enabledProviders, "providers", "p", defaultProviders, "The providers to use for searching, if not provided the providers of the profile will be used")
+	Cmd.Flags().BoolVar(&seeds, "seeds", false, "Run the seed queries of the profile")
+	Cmd.Flags().StringVar(&resumeRun, "resume", "", "Continue an interrupted run from its checkpoints")
+	Cmd.Flags().DurationVar(&timeout, "timeout", providers.DefaultTimeout, "Overall timeout of each provider's search for a query")
+	Cmd.Flags().StringVar(&debugArtifactsDir, "debug-artifacts", "", "Save a screenshot, the rendered HTML, the console log and the last network requests of failed searches to a directory of the run inside this directory")
+	Cmd.MarkFlagsOneRequired("query", "queries-file", "from-queries", "seeds", "resume")
+	Cmd.MarkFlagsMutuallyExclusive("query", "resume")
+	Cmd.MarkFlagsMutuallyExclusive("queries-file", "resume")
+	Cmd.MarkFlagsMutuallyExclusive("from-queries", "resume")
+	Cmd.MarkFlagsMutuallyExclusive("seeds", "resume")
 }
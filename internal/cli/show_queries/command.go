@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -14,12 +17,20 @@ import (
 var (
 	use   = "show-queries"
 	short = "Display all search queries stored in the database"
-	long  = "Display all search queries that have been generated and stored in the database, including whether they have been used in searches."
+	long  = `Display all search queries that have been generated and stored in the database, including whether they have been used in searches.
+
+With --stats, display the yield of each query instead: how many new articles it found, the share of them
+approved by the AI check, how many videos were downloaded and how many articles have relevant timestamps.
+Queries are sorted by yield, highest first. Ad hoc queries, which were given to the search command
+instead of being generated or expanded from a template, are left out. With --by-variable, the yields of queries expanded from
+templates are summed per value of the given template variable instead.`
 
 	// Command flags
 	outputFile string
 	jsonFormat bool
 	onlyUnused bool
+	showStats  bool
+	byVariable string
 )
 
 var Cmd = &cobra.Command{
@@ -33,18 +44,25 @@ func init() {
 	Cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (if not specified, outputs to stdout)")
 	Cmd.Flags().BoolVarP(&jsonFormat, "json", "j", false, "Output in JSON format")
 	Cmd.Flags().BoolVarP(&onlyUnused, "unused", "u", false, "Show only unused queries")
+	Cmd.Flags().BoolVarP(&showStats, "stats", "s", false, "Show the yield of each query, highest first")
+	Cmd.Flags().StringVar(&byVariable, "by-variable", "", "With --stats, sum the yield of template queries per value of this template variable")
 }
 
 func run(cmd *cobra.Command, args []string) {
 	// Initialize database connection
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 		return
 	}
 
 	ctx := context.Background()
 
+	if showStats {
+		writeOutput(statsOutput(ctx))
+		return
+	}
+
 	var queries []*db.Query
 	var err error
 
@@ -84,11 +102,80 @@ func run(cmd *cobra.Command, args []string) {
 			}
 
 			output += fmt.Sprintf("[%d][%s] %s", i+1, usedStatus, q.Query)
+			if q.AdHoc {
+				output += " (ad hoc)"
+			}
 
 			output += "\n"
 		}
 	}
 
+	writeOutput(output)
+}
+
+// statsOutput formats the yield of every query
+func statsOutput(ctx context.Context) string {
+	log.Info().Msg("computing query yields")
+	yields, err := sleuth.QueryYields(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to compute query yields")
+	}
+	if onlyUnused {
+		unused := yields[:0]
+		for _, y := range yields {
+			if !y.Used {
+				unused = append(unused, y)
+			}
+		}
+		yields = unused
+	}
+
+	if byVariable != "" {
+		return variableStatsOutput(sleuth.GroupYieldsByVariable(yields, byVariable))
+	}
+
+	if jsonFormat {
+		jsonData, err := json.MarshalIndent(yields, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to marshal query yields to JSON")
+		}
+		return string(jsonData)
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "QUERY\tRUNS\tNEW\tAPPROVED\tAPPROVED %\tDOWNLOADED\tTIMESTAMPS")
+	for _, y := range yields {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.0f%%\t%d\t%d\n",
+			y.Query, y.Runs, y.NewArticles, y.Approved, y.ApprovedShare*100, y.Downloaded, y.WithTimestamps)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// variableStatsOutput formats the yield of every value of a template variable
+func variableStatsOutput(grouped []sleuth.VariableYield) string {
+	if jsonFormat {
+		jsonData, err := json.MarshalIndent(grouped, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to marshal variable yields to JSON")
+		}
+		return string(jsonData)
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tQUERIES\tNEW\tAPPROVED\tAPPROVED %%\tDOWNLOADED\tTIMESTAMPS\n", strings.ToUpper(byVariable))
+	for _, g := range grouped {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.0f%%\t%d\t%d\n",
+			g.Value, g.Queries, g.NewArticles, g.Approved, g.ApprovedShare*100, g.Downloaded, g.WithTimestamps)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// writeOutput writes the output to the output file, or stdout if none was given
+func writeOutput(output string) {
 	// Output to file or stdout
 	if outputFile != "" {
 		if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
@@ -0,0 +1,140 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	use   = "status"
+	short = "Show how many articles went through each stage of the pipeline"
+	long  = `Show how many articles are done, started or failed in each stage of the pipeline, and list the
+articles that are stuck: they failed a stage, or started one and have not moved for longer than --stuck-after.`
+
+	// Command flags
+	stuckAfter time.Duration
+	limit      int
+	jsonFormat bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+	Long:  long,
+	Run:   run,
+}
+
+func init() {
+	Cmd.Flags().DurationVar(&stuckAfter, "stuck-after", time.Hour, "How long an article can stay in a started stage before it is stuck")
+	Cmd.Flags().IntVarP(&limit, "limit", "l", 20, "Number of stuck articles to list (0 means no limit)")
+	Cmd.Flags().BoolVarP(&jsonFormat, "json", "j", false, "Output in JSON format")
+}
+
+// StageCount counts the articles in every state of a stage.
+type StageCount struct {
+	Stage      db.Stage `json:"stage"`
+	Done       int64    `json:"done"`
+	Started    int64    `json:"started"`
+	Failed     int64    `json:"failed"`
+	NotStarted int64    `json:"notStarted"`
+}
+
+// StuckArticle is an article that needs attention in a stage.
+type StuckArticle struct {
+	Id    primitive.ObjectID `json:"id"`
+	Url   string             `json:"url"`
+	Stage db.Stage           `json:"stage"`
+	db.StageStatus
+}
+
+// Status is the progress of every article through the pipeline.
+type Status struct {
+	Articles int64          `json:"articles"`
+	Stages   []StageCount   `json:"stages"`
+	Stuck    []StuckArticle `json:"stuck"`
+}
+
+func run(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+
+	var status Status
+	var err error
+	status.Articles, err = db.Models.CountArticles(ctx, bson.M{})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to count articles")
+	}
+
+	now := time.Now()
+	for _, stage := range db.Stages {
+		count := StageCount{Stage: stage}
+		for state, n := range map[db.StageState]*int64{db.StageDone: &count.Done, db.StageStarted: &count.Started, db.StageFailed: &count.Failed} {
+			*n, err = db.Models.CountArticles(ctx, bson.M{"stages." + string(stage) + ".state": string(state)})
+			if err != nil {
+				log.Fatal().Err(err).Str("stage", string(stage)).Msg("failed to count articles")
+			}
+		}
+		count.NotStarted = status.Articles - count.Done - count.Started - count.Failed
+		status.Stages = append(status.Stages, count)
+
+		if limit > 0 && len(status.Stuck) >= limit {
+			continue
+		}
+		filter := bson.M{"stages." + string(stage) + ".state": bson.M{"$in": []string{string(db.StageStarted), string(db.StageFailed)}}}
+		projection := bson.M{"url": 1, "stages." + string(stage): 1}
+		for article, err := range db.Models.ScanArticles(ctx, filter, db.ScanOptions{Projection: projection}) {
+			if err != nil {
+				log.Fatal().Err(err).Str("stage", string(stage)).Msg("failed to find stuck articles")
+			}
+			stageStatus := article.Stages[stage]
+			if !stageStatus.Stuck(now, stuckAfter) {
+				continue
+			}
+			status.Stuck = append(status.Stuck, StuckArticle{Id: article.Id, Url: article.Url, Stage: stage, StageStatus: stageStatus})
+			if limit > 0 && len(status.Stuck) >= limit {
+				break
+			}
+		}
+	}
+
+	if jsonFormat {
+		jsonData, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to marshal to JSON")
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	fmt.Printf("Articles: %d\n\n", status.Articles)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STAGE\tDONE\tSTARTED\tFAILED\tNOT STARTED")
+	for _, c := range status.Stages {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", c.Stage, c.Done, c.Started, c.Failed, c.NotStarted)
+	}
+	w.Flush()
+
+	if len(status.Stuck) == 0 {
+		fmt.Println("\nNo stuck articles")
+		return
+	}
+	fmt.Println("\nStuck articles:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTAGE\tSTATE\tATTEMPTS\tSINCE\tURL\tLAST ERROR")
+	for _, s := range status.Stuck {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", s.Id.Hex(), s.Stage, s.State, s.Attempts, s.UpdatedAt.Local().Format(time.DateTime), s.Url, s.LastError)
+	}
+	w.Flush()
+}
@@ -0,0 +1,166 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/cli/aicheck"
+	downloadVideos "github.com/giraffesyo/sleuth/internal/cli/download_videos"
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth"
+	"github.com/giraffesyo/sleuth/internal/sleuth/cron"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	use   = "watch"
+	short = "Run saved query sets against providers on cron schedules"
+	long  = `Run continuously and search the providers whenever a schedule is due. Schedules are stored in the
+database with "watch add", so they survive restarts. Each due run is claimed in the database before it
+starts, so restarting the watcher or running several watchers never runs a schedule twice. A schedule
+that fell due while no watcher was running is run once when the watcher starts.
+
+Schedules can chain the AI check on the new articles, and the download of the ones it approves.`
+
+	// Command flags
+	pollInterval      time.Duration
+	debugArtifactsDir string
+)
+
+var Cmd = &cobra.Command{
+	Use:   use,
+	Short: short,
+	Long:  long,
+	Run:   run,
+}
+
+func init() {
+	Cmd.Flags().DurationVar(&pollInterval, "poll", time.Minute, "How often to check for due schedules")
+	Cmd.Flags().StringVar(&debugArtifactsDir, "debug-artifacts", "", "Save a screenshot, the rendered HTML, the console log and the last network requests of failed searches to a directory of the run inside this directory")
+	Cmd.AddCommand(addCmd)
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(removeCmd)
+}
+
+func run(cmd *cobra.Command, args []string) {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+
+	log.Info().Dur("poll", pollInterval).Msg("watching schedules")
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		runDueSchedules(ctx)
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("stopped watching schedules")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDueSchedules runs every schedule whose next run is due, one at a time
+func runDueSchedules(ctx context.Context) {
+	schedules, err := db.Models.FindAllSchedules(ctx)
+	if err != nil {
+		log.Err(err).Msg("failed to find schedules")
+		return
+	}
+
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			return
+		}
+		now := time.Now()
+		if schedule.NextRunAt.After(now) {
+			continue
+		}
+
+		parsed, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			log.Err(err).Str("schedule", schedule.Name).Msg("invalid cron expression, skipping")
+			continue
+		}
+		// Runs missed while no watcher was running collapse into this one
+		claimed, err := db.Models.ClaimScheduleRun(ctx, schedule.Id, schedule.NextRunAt, parsed.Next(now))
+		if err != nil {
+			log.Err(err).Str("schedule", schedule.Name).Msg("failed to claim schedule run")
+			continue
+		}
+		if !claimed {
+			log.Debug().Str("schedule", schedule.Name).Msg("schedule run was claimed elsewhere, skipping")
+			continue
+		}
+
+		result := runSchedule(ctx, schedule)
+		if err := db.Models.RecordScheduleResult(ctx, schedule.Id, result); err != nil {
+			log.Err(err).Str("schedule", schedule.Name).Msg("failed to record schedule result")
+		}
+	}
+}
+
+// runSchedule searches for the schedule's queries and chains the AI check and download on the new articles
+func runSchedule(ctx context.Context, schedule *db.Schedule) db.ScheduleResult {
+	result := db.ScheduleResult{StartedAt: time.Now()}
+	log.Info().Str("schedule", schedule.Name).Msg("running schedule")
+
+	s := sleuth.NewSleuth(
+		sleuth.WithProvider(schedule.Providers...),
+		sleuth.WithSearchQueries(schedule.Queries...),
+		sleuth.WithUnusedQueries(schedule.FromQueries),
+		sleuth.WithDebugArtifacts(debugArtifactsDir),
+	)
+	summary, err := s.Run(ctx)
+	if err != nil {
+		log.Err(err).Str("schedule", schedule.Name).Msg("schedule search failed")
+		result.Error = err.Error()
+	}
+	var found []db.Article
+	if summary != nil {
+		found = summary.Articles()
+		for _, p := range summary.ByProvider() {
+			for query, failure := range p.Failures {
+				log.Error().Str("schedule", schedule.Name).Str("provider", p.Provider).Str("query", query).Str("error", failure).Msg("provider failed")
+			}
+		}
+		if summary.Failed() && result.Error == "" {
+			result.Error = "one or more providers failed"
+		}
+	}
+	result.NewArticles = len(found)
+
+	articles := make([]*db.Article, len(found))
+	for i := range found {
+		articles[i] = &found[i]
+	}
+
+	if schedule.ChainAicheck && len(articles) > 0 {
+		result.Checked = aicheck.CheckArticles(ctx, articles)
+	}
+	if schedule.ChainDownload {
+		var approved []*db.Article
+		for _, article := range articles {
+			if article.AiSuggestsDownloadingVideo {
+				approved = append(approved, article)
+			}
+		}
+		if len(approved) > 0 {
+			result.Downloaded = downloadVideos.DownloadArticles(ctx, slices.Values(approved))
+		}
+	}
+
+	result.FinishedAt = time.Now()
+	log.Info().Str("schedule", schedule.Name).Int("new", result.NewArticles).Int("checked", result.Checked).Int("downloaded", result.Downloaded).Msg("finished schedule")
+	return result
+}
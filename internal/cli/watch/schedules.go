@@ -0,0 +1,164 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/profile"
+	"github.com/giraffesyo/sleuth/internal/sleuth/cron"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// add flags
+	name          string
+	cronSpec      string
+	queries       []string
+	fromQueries   bool
+	providerNames []string
+	chainAicheck  bool
+	chainDownload bool
+
+	// list flags
+	jsonFormat bool
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a schedule for the watch command",
+	Example: `  sleuth watch add --name lakes --cron "0 */6 * * *" -q "body found in lake" -q "remains found lake" --aicheck --download
+  sleuth watch add --name generated --cron "@daily" --from-queries -p cnn`,
+	Run: runAdd,
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the schedules and the result of their last run",
+	Run:   runList,
+}
+
+var removeCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a schedule",
+	Args:  cobra.ExactArgs(1),
+	Run:   runRemove,
+}
+
+func init() {
+	addCmd.Flags().StringVarP(&name, "name", "n", "", "Unique name of the schedule")
+	addCmd.Flags().StringVarP(&cronSpec, "cron", "c", "", `Five-field cron expression, e.g. "0 */6 * * *", or a macro such as @daily`)
+	addCmd.Flags().StringArrayVarP(&queries, "query", "q", nil, "Search terms to run. Can be repeated")
+	addCmd.Flags().BoolVar(&fromQueries, "from-queries", false, "Also run every unused query from the queries collection")
+	addCmd.Flags().StringSliceVarP(&providerNames, "providers", "p", nil, "The providers to search, the ones of the profile if not provided")
+	addCmd.Flags().BoolVar(&chainAicheck, "aicheck", false, "Run the AI check on new articles")
+	addCmd.Flags().BoolVar(&chainDownload, "download", false, "Download the videos of new articles approved by the AI check (implies --aicheck)")
+	addCmd.MarkFlagRequired("name")
+	addCmd.MarkFlagRequired("cron")
+	addCmd.MarkFlagsOneRequired("query", "from-queries")
+
+	listCmd.Flags().BoolVarP(&jsonFormat, "json", "j", false, "Output in JSON format")
+}
+
+func runAdd(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	parsed, err := cron.Parse(cronSpec)
+	if err != nil {
+		log.Fatal().Err(err).Str("cron", cronSpec).Msg("invalid cron expression")
+	}
+	nextRunAt := parsed.Next(time.Now())
+	if nextRunAt.IsZero() {
+		log.Fatal().Str("cron", cronSpec).Msg("cron expression never fires")
+	}
+
+	if !cmd.Flags().Changed("providers") {
+		providerNames = profile.Active.Providers
+	}
+
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+
+	if _, err := db.Models.FindScheduleByName(ctx, name); err == nil {
+		log.Fatal().Str("name", name).Msg("a schedule with this name already exists")
+	}
+
+	schedule := &db.Schedule{
+		Name:          name,
+		Cron:          cronSpec,
+		Queries:       queries,
+		FromQueries:   fromQueries,
+		Providers:     providerNames,
+		ChainAicheck:  chainAicheck || chainDownload,
+		ChainDownload: chainDownload,
+		NextRunAt:     nextRunAt,
+	}
+	if err := db.Models.CreateSchedule(ctx, schedule); err != nil {
+		log.Fatal().Err(err).Msg("failed to save schedule")
+	}
+	log.Info().Str("name", name).Time("nextRunAt", nextRunAt).Msg("added schedule")
+}
+
+func runList(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+
+	schedules, err := db.Models.FindAllSchedules(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to find schedules")
+	}
+
+	if jsonFormat {
+		jsonData, err := json.MarshalIndent(schedules, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to marshal schedules to JSON")
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCRON\tQUERIES\tPROVIDERS\tNEXT RUN\tLAST RUN\tLAST RESULT")
+	for _, s := range schedules {
+		querySet := strings.Join(s.Queries, "; ")
+		if s.FromQueries {
+			querySet = strings.TrimPrefix(querySet+"; unused queries", "; ")
+		}
+		lastRun, lastResult := "never", ""
+		if !s.LastRunAt.IsZero() {
+			lastRun = s.LastRunAt.Local().Format(time.DateTime)
+		}
+		if r := s.LastResult; r != nil {
+			lastResult = fmt.Sprintf("%d new, %d checked, %d downloaded", r.NewArticles, r.Checked, r.Downloaded)
+			if r.Error != "" {
+				lastResult += ", failed: " + r.Error
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.Name, s.Cron, querySet, strings.Join(s.Providers, ","), s.NextRunAt.Local().Format(time.DateTime), lastRun, lastResult)
+	}
+	w.Flush()
+}
+
+func runRemove(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+
+	if err := db.Models.DeleteScheduleByName(ctx, args[0]); err != nil {
+		log.Fatal().Err(err).Str("name", args[0]).Msg("failed to remove schedule")
+	}
+	log.Info().Str("name", args[0]).Msg("removed schedule")
+}
@@ -0,0 +1,306 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BackupCollections are the collections a backup holds, in the order they are written.
+var BackupCollections = []string{articlesBucket, queriesBucket}
+
+// RestoreMode selects what importing a backup does with the documents that are already stored.
+type RestoreMode string
+
+const (
+	// RestoreMerge keeps the stored documents, and only imports the documents whose ID,
+	// or url for articles, is not stored yet.
+	RestoreMerge RestoreMode = "merge"
+	// RestoreReplace deletes every stored document of the backed up collections before importing.
+	RestoreReplace RestoreMode = "replace"
+)
+
+// importBatchSize is the number of documents of a collection that are inserted at a time.
+const importBatchSize = 500
+
+// backupLine is a line of a backup: a stored document as MongoDB Extended JSON, in canonical mode so
+// that ObjectIDs, dates and the types of numbers are kept exactly.
+type backupLine struct {
+	Collection string   `bson:"collection"`
+	Document   bson.Raw `bson:"document"`
+}
+
+// ImportCount is the number of documents of a collection that an import inserted and skipped.
+type ImportCount struct {
+	Imported int64
+	Skipped  int64
+}
+
+func checkBackupCollection(collection string) error {
+	if !slices.Contains(BackupCollections, collection) {
+		return fmt.Errorf("unknown collection %q, backups hold %v", collection, BackupCollections)
+	}
+	return nil
+}
+
+// ExportBackup writes every document of the BackupCollections to w, one JSON document per line,
+// including the fields the models do not know. It returns the number of documents of every collection.
+func ExportBackup(ctx context.Context, store Store, w io.Writer) (map[string]int64, error) {
+	counts := map[string]int64{}
+	buffered := bufio.NewWriter(w)
+	for _, collection := range BackupCollections {
+		for doc, err := range store.ScanDocuments(ctx, collection, ScanOptions{}) {
+			if err != nil {
+				return counts, fmt.Errorf("failed to read %s: %w", collection, err)
+			}
+			line, err := bson.MarshalExtJSON(backupLine{Collection: collection, Document: doc}, true, false)
+			if err != nil {
+				return counts, fmt.Errorf("failed to encode a document of %s: %w", collection, err)
+			}
+			if _, err := buffered.Write(append(line, '\n')); err != nil {
+				return counts, err
+			}
+			counts[collection]++
+		}
+	}
+	return counts, buffered.Flush()
+}
+
+// readBackup reads the documents of a backup written by ExportBackup, checking that every document
+// belongs to one of the BackupCollections and has an ObjectID.
+func readBackup(r io.Reader) iter.Seq2[backupLine, error] {
+	return func(yield func(backupLine, error) bool) {
+		reader := bufio.NewReader(r)
+		for number := 1; ; number++ {
+			// lines of articles with embeddings are too long for a bufio.Scanner
+			data, err := reader.ReadBytes('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				yield(backupLine{}, err)
+				return
+			}
+			if data = bytes.TrimSpace(data); len(data) > 0 {
+				var line backupLine
+				if lineErr := bson.UnmarshalExtJSON(data, true, &line); lineErr != nil {
+					yield(line, fmt.Errorf("line %d: %w", number, lineErr))
+					return
+				}
+				if lineErr := checkBackupCollection(line.Collection); lineErr != nil {
+					yield(line, fmt.Errorf("line %d: %w", number, lineErr))
+					return
+				}
+				if _, ok := line.Document.Lookup("_id").ObjectIDOK(); !ok {
+					yield(line, fmt.Errorf("line %d: document has no ObjectID", number))
+					return
+				}
+				if !yield(line, nil) {
+					return
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+		}
+	}
+}
+
+// ImportBackup imports a backup written by ExportBackup into store. The whole backup is read once
+// before anything is changed, so that an invalid backup never leaves a replaced store half empty.
+func ImportBackup(ctx context.Context, store Store, r io.ReadSeeker, mode RestoreMode) (map[string]ImportCount, error) {
+	if mode != RestoreMerge && mode != RestoreReplace {
+		return nil, fmt.Errorf("unknown restore mode %q, expected %s or %s", mode, RestoreMerge, RestoreReplace)
+	}
+	for _, err := range readBackup(r) {
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup: %w", err)
+		}
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if mode == RestoreReplace {
+		for _, collection := range BackupCollections {
+			if err := store.DeleteAllDocuments(ctx, collection); err != nil {
+				return nil, fmt.Errorf("failed to delete %s: %w", collection, err)
+			}
+		}
+	}
+
+	counts := map[string]ImportCount{}
+	batches := map[string][]bson.Raw{}
+	flush := func(collection string) error {
+		batch := batches[collection]
+		if len(batch) == 0 {
+			return nil
+		}
+		inserted, err := store.InsertDocuments(ctx, collection, batch)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", collection, err)
+		}
+		count := counts[collection]
+		count.Imported += inserted
+		count.Skipped += int64(len(batch)) - inserted
+		counts[collection] = count
+		batches[collection] = batch[:0]
+		return nil
+	}
+	for line, err := range readBackup(r) {
+		if err != nil {
+			return counts, err
+		}
+		batches[line.Collection] = append(batches[line.Collection], line.Document)
+		if len(batches[line.Collection]) == importBatchSize {
+			if err := flush(line.Collection); err != nil {
+				return counts, err
+			}
+		}
+	}
+	for _, collection := range BackupCollections {
+		if err := flush(collection); err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+func (c *Mongo) backupCollection(collection string) (*mongo.Collection, error) {
+	if err := checkBackupCollection(collection); err != nil {
+		return nil, err
+	}
+	return c.database().Collection(collection), nil
+}
+
+// ScanDocuments streams every stored document of one of the BackupCollections, in the order they were inserted.
+func (c *Mongo) ScanDocuments(ctx context.Context, collection string, opts ScanOptions) iter.Seq2[bson.Raw, error] {
+	coll, err := c.backupCollection(collection)
+	if err != nil {
+		return func(yield func(bson.Raw, error) bool) { yield(nil, err) }
+	}
+	return dereference(scanCollection[bson.Raw](ctx, coll, bson.M{}, opts))
+}
+
+// InsertDocuments inserts documents into one of the BackupCollections as they are, skipping the documents
+// whose ID, or url for articles, is already stored. It returns the number of documents it inserted.
+func (c *Mongo) InsertDocuments(ctx context.Context, collection string, docs []bson.Raw) (int64, error) {
+	coll, err := c.backupCollection(collection)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	insert := make([]any, len(docs))
+	for i, doc := range docs {
+		insert[i] = doc
+	}
+	// unordered, so that the documents after a duplicate are still inserted
+	_, err = coll.InsertMany(ctx, insert, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return 0, err
+			}
+		}
+		return int64(len(docs) - len(bulkErr.WriteErrors)), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(docs)), nil
+}
+
+// DeleteAllDocuments deletes every document of one of the BackupCollections. Its indexes are kept.
+func (c *Mongo) DeleteAllDocuments(ctx context.Context, collection string) error {
+	coll, err := c.backupCollection(collection)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = coll.DeleteMany(ctx, bson.M{})
+	return err
+}
+
+func (s *documentStore) ScanDocuments(ctx context.Context, collection string, opts ScanOptions) iter.Seq2[bson.Raw, error] {
+	if err := checkBackupCollection(collection); err != nil {
+		return func(yield func(bson.Raw, error) bool) { yield(nil, err) }
+	}
+	return dereference(scanDocuments[bson.Raw](ctx, s.backend, collection, nil, opts))
+}
+
+func (s *documentStore) InsertDocuments(ctx context.Context, collection string, docs []bson.Raw) (int64, error) {
+	if err := checkBackupCollection(collection); err != nil {
+		return 0, err
+	}
+	var inserted int64
+	err := s.backend.update(func(tx documentTx) error {
+		for _, doc := range docs {
+			id, ok := doc.Lookup("_id").ObjectIDOK()
+			if !ok {
+				return errors.New("document has no ObjectID")
+			}
+			if tx.get(collection, id[:]) != nil {
+				continue
+			}
+			if collection == articlesBucket {
+				url, _ := doc.Lookup("url").StringValueOK()
+				if tx.get(articleUrlsBucket, []byte(url)) != nil {
+					continue
+				}
+				if err := tx.put(articleUrlsBucket, []byte(url), id[:]); err != nil {
+					return err
+				}
+			}
+			if err := tx.put(collection, id[:], doc); err != nil {
+				return err
+			}
+			inserted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+func (s *documentStore) DeleteAllDocuments(ctx context.Context, collection string) error {
+	if err := checkBackupCollection(collection); err != nil {
+		return err
+	}
+	buckets := []string{collection}
+	if collection == articlesBucket {
+		buckets = append(buckets, articleUrlsBucket)
+	}
+	return s.backend.update(func(tx documentTx) error {
+		for _, bucket := range buckets {
+			var keys [][]byte
+			// bolt does not allow deleting while iterating
+			err := tx.forEach(bucket, func(key, value []byte) error {
+				keys = append(keys, bytes.Clone(key))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if err := tx.delete(bucket, key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func storedDocuments(t *testing.T, store Store, collection string) []bson.Raw {
+	var docs []bson.Raw
+	for doc, err := range store.ScanDocuments(t.Context(), collection, ScanOptions{}) {
+		require.NoError(t, err)
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestBackup(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := t.Context()
+
+		article := &Article{Url: "https://www.cnn.com/2025/02/26/world/video/a", Title: "A", CaseId: 7}
+		require.NoError(t, store.CreateArticle(ctx, article))
+		// fields that Article does not model, as the clustering pipeline adds them
+		require.NoError(t, store.UpdateArticle(ctx, article.Id, bson.M{"cluster": int64(3), "embed": bson.A{0.25, -1.5}}))
+		require.NoError(t, store.CreateQuery(ctx, &Query{Query: "body found in lake"}))
+
+		var backup bytes.Buffer
+		counts, err := ExportBackup(ctx, store, &backup)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int64{"articles": 1, "queries": 1}, counts)
+
+		// replacing restores every document exactly
+		restored := NewMemory()
+		require.NoError(t, restored.CreateArticle(ctx, &Article{Url: "https://www.cnn.com/other"}))
+		imported, err := ImportBackup(ctx, restored, bytes.NewReader(backup.Bytes()), RestoreReplace)
+		require.NoError(t, err)
+		require.Equal(t, ImportCount{Imported: 1}, imported["articles"])
+		for _, collection := range BackupCollections {
+			require.Equal(t, storedDocuments(t, store, collection), storedDocuments(t, restored, collection))
+		}
+		found, err := restored.FindArticleByUrl(ctx, article.Url)
+		require.NoError(t, err)
+		require.Equal(t, article.Id, found.Id)
+		_, err = restored.FindArticleByUrl(ctx, "https://www.cnn.com/other")
+		require.Error(t, err)
+
+		// merging skips the documents that are already stored, by ID or url
+		merged := NewMemory()
+		require.NoError(t, merged.CreateArticle(ctx, &Article{Url: article.Url, Title: "Kept"}))
+		imported, err = ImportBackup(ctx, merged, bytes.NewReader(backup.Bytes()), RestoreMerge)
+		require.NoError(t, err)
+		require.Equal(t, ImportCount{Skipped: 1}, imported["articles"])
+		require.Equal(t, ImportCount{Imported: 1}, imported["queries"])
+		found, err = merged.FindArticleByUrl(ctx, article.Url)
+		require.NoError(t, err)
+		require.Equal(t, "Kept", found.Title)
+		imported, err = ImportBackup(ctx, merged, bytes.NewReader(backup.Bytes()), RestoreMerge)
+		require.NoError(t, err)
+		require.Equal(t, ImportCount{Skipped: 1}, imported["queries"])
+
+		// an invalid backup changes nothing
+		invalid := backup.String() + `{"collection": "schedules", "document": {"_id": {"$oid": "67bf1c2e9d1f4a6b8c3d2e10"}}}` + "\n"
+		_, err = ImportBackup(ctx, restored, strings.NewReader(invalid), RestoreReplace)
+		require.ErrorContains(t, err, "line 3")
+		require.Equal(t, storedDocuments(t, store, "articles"), storedDocuments(t, restored, "articles"))
+	})
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+// Bolt stores the collections in a single-file embedded database, so that a dataset can be
+// carried around without running MongoDB. Only one process can open the file at a time.
+type Bolt struct {
+	documentStore
+	db *bbolt.DB
+}
+
+// boltPath returns the file of DatabaseName for the file of a bolt:// URI. The default database is
+// stored in the file itself, and any other one next to it in a file named after the database,
+// e.g. sleuth-wildfires.db, so that profiles never share their articles.
+func boltPath(path string) string {
+	if path == "" || DatabaseName == defaultDatabase {
+		return path
+	}
+	return filepath.Join(filepath.Dir(path), DatabaseName+filepath.Ext(path))
+}
+
+// OpenBolt opens the embedded database at path, creating it if it does not exist.
+func OpenBolt(path string) (*Bolt, error) {
+	if path == "" {
+		return nil, errors.New("the bolt database URI needs a file path, e.g. bolt://sleuth.db")
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range documentBuckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+	log.Info().Str("path", path).Msg("Opened embedded database!")
+	return &Bolt{documentStore: documentStore{backend: boltBackend{db}}, db: db}, nil
+}
+
+// Close closes the database file.
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+type boltBackend struct {
+	db *bbolt.DB
+}
+
+func (b boltBackend) view(fn func(tx documentTx) error) error {
+	return b.db.View(func(tx *bbolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+func (b boltBackend) update(fn func(tx documentTx) error) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+type boltTx struct {
+	tx *bbolt.Tx
+}
+
+func (t boltTx) get(bucket string, key []byte) []byte {
+	return t.tx.Bucket([]byte(bucket)).Get(key)
+}
+
+func (t boltTx) put(bucket string, key, value []byte) error {
+	return t.tx.Bucket([]byte(bucket)).Put(key, value)
+}
+
+func (t boltTx) delete(bucket string, key []byte) error {
+	return t.tx.Bucket([]byte(bucket)).Delete(key)
+}
+
+func (t boltTx) forEach(bucket string, fn func(key, value []byte) error) error {
+	return t.tx.Bucket([]byte(bucket)).ForEach(fn)
+}
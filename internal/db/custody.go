@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CustodyEvent is what happened to a media file recorded in the custody log.
+type CustodyEvent string
+
+const (
+	// CustodyCaptured records that the media was downloaded and saved.
+	CustodyCaptured CustodyEvent = "captured"
+	// CustodyDisposed records that the saved media was deleted on purpose, with its hash right before.
+	CustodyDisposed CustodyEvent = "disposed"
+)
+
+// CustodyEntry records the capture or disposal of a media file in the custody log. Every entry contains
+// the hash of the entry before it, so that changing, inserting or removing an entry breaks the chain.
+type CustodyEntry struct {
+	Id           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Sequence     int64               `bson:"sequence" json:"sequence"` // Position in the chain, starting at 1
+	Event        CustodyEvent        `bson:"event" json:"event"`
+	ArticleId    primitive.ObjectID  `bson:"articleId" json:"articleId"`
+	SourceUrl    string              `bson:"sourceUrl" json:"sourceUrl"` // Page the media was found on
+	MediaUrl     string              `bson:"mediaUrl" json:"mediaUrl"`   // Url the media was downloaded from
+	Path         string              `bson:"path" json:"path"`           // File the media was saved to
+	StatusCode   int                 `bson:"statusCode" json:"statusCode"`
+	Headers      map[string][]string `bson:"headers" json:"headers"` // Headers of the HTTP response
+	Bytes        int64               `bson:"bytes" json:"bytes"`
+	SHA256       string              `bson:"sha256" json:"sha256"`
+	CapturedAt   time.Time           `bson:"capturedAt" json:"capturedAt"`     // When the event happened
+	PreviousHash string              `bson:"previousHash" json:"previousHash"` // Hash of the entry before, empty for the first
+	Hash         string              `bson:"hash" json:"hash"`                 // SHA-256 of the entry, see computeHash
+}
+
+// computeHash returns the SHA-256 of every field of the entry apart from its ID and hash.
+// The fields are written as JSON in a fixed order, and the time in milliseconds, as it is stored.
+func (e *CustodyEntry) computeHash() string {
+	fields := []any{
+		e.Sequence,
+		e.Event,
+		e.ArticleId.Hex(),
+		e.SourceUrl,
+		e.MediaUrl,
+		e.Path,
+		e.StatusCode,
+		e.Headers, // maps are written with their keys sorted
+		e.Bytes,
+		e.SHA256,
+		e.CapturedAt.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		e.PreviousHash,
+	}
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		// every field can be marshaled
+		panic(err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// chainTo links the entry to the last entry of the log, which is nil if the log is empty, and hashes it.
+func (e *CustodyEntry) chainTo(last *CustodyEntry) {
+	e.Sequence = 1
+	e.PreviousHash = ""
+	if last != nil {
+		e.Sequence = last.Sequence + 1
+		e.PreviousHash = last.Hash
+	}
+	// times are stored in milliseconds
+	e.CapturedAt = e.CapturedAt.UTC().Truncate(time.Millisecond)
+	e.Hash = e.computeHash()
+}
+
+// CustodyProblem is a way in which the custody log, or a file it recorded, was changed since capture.
+type CustodyProblem struct {
+	Sequence int64  `json:"sequence"` // Entry the problem was found in
+	Problem  string `json:"problem"`
+}
+
+// VerifyCustodyChain checks that the entries, in the order of their sequence, form an unbroken chain.
+func VerifyCustodyChain(entries []*CustodyEntry) []CustodyProblem {
+	var problems []CustodyProblem
+	var previous *CustodyEntry
+	for _, entry := range entries {
+		expectedSequence, expectedPrevious := int64(1), ""
+		if previous != nil {
+			expectedSequence, expectedPrevious = previous.Sequence+1, previous.Hash
+		}
+		if entry.Sequence != expectedSequence {
+			problems = append(problems, CustodyProblem{entry.Sequence, fmt.Sprintf("expected sequence %d, entries are missing", expectedSequence)})
+		}
+		if entry.PreviousHash != expectedPrevious {
+			problems = append(problems, CustodyProblem{entry.Sequence, "does not link to the entry before it"})
+		}
+		if entry.computeHash() != entry.Hash {
+			problems = append(problems, CustodyProblem{entry.Sequence, "was changed after it was recorded"})
+		}
+		previous = entry
+	}
+	return problems
+}
+
+// Disposed reports whether the entry records the disposal of the file.
+func (e *CustodyEntry) Disposed() bool {
+	return e.Event == CustodyDisposed
+}
+
+// HashFile returns the SHA-256 and the size of a file, as they are recorded in the custody log.
+func HashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+func (c *Mongo) custody() *mongo.Collection {
+	return c.database().Collection("custody")
+}
+
+// ensureCustodySequenceIndex keeps sequences unique, so that two entries appended at the same time
+// cannot both follow the same entry.
+func ensureCustodySequenceIndex(collection *mongo.Collection) error {
+	indexModel := mongo.IndexModel{
+		Keys:    bson.M{"sequence": 1},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := collection.Indexes().CreateOne(context.Background(), indexModel); err != nil {
+		return fmt.Errorf("failed to create custody index: %w", err)
+	}
+	return nil
+}
+
+// AppendCustodyEntry adds an entry to the end of the custody log, setting its sequence and hashes.
+func (c *Mongo) AppendCustodyEntry(ctx context.Context, entry *CustodyEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	for {
+		var last *CustodyEntry
+		var found CustodyEntry
+		err := c.custody().FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"sequence": -1})).Decode(&found)
+		switch {
+		case err == nil:
+			last = &found
+		case err != mongo.ErrNoDocuments:
+			return err
+		}
+
+		entry.chainTo(last)
+		entry.Id = primitive.NewObjectID()
+		_, err = c.custody().InsertOne(ctx, entry)
+		// another entry was appended since the last one was read, chain to that one instead
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		return err
+	}
+}
+
+// FindCustodyEntries returns the whole custody log, in the order of the chain.
+func (c *Mongo) FindCustodyEntries(ctx context.Context) ([]*CustodyEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cursor, err := c.custody().Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"sequence": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*CustodyEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *documentStore) AppendCustodyEntry(ctx context.Context, entry *CustodyEntry) error {
+	return s.backend.update(func(tx documentTx) error {
+		entries, err := findDocuments[CustodyEntry](tx, custodyBucket, nil)
+		if err != nil {
+			return err
+		}
+		var last *CustodyEntry
+		for _, e := range entries {
+			if last == nil || e.Sequence > last.Sequence {
+				last = e
+			}
+		}
+		entry.chainTo(last)
+		entry.Id = primitive.NewObjectID()
+		return putDocument(tx, custodyBucket, entry.Id, entry)
+	})
+}
+
+func (s *documentStore) FindCustodyEntries(ctx context.Context) ([]*CustodyEntry, error) {
+	var entries []*CustodyEntry
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		entries, err = findDocuments[CustodyEntry](tx, custodyBucket, nil)
+		return err
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Sequence < entries[j].Sequence })
+	return entries, err
+}
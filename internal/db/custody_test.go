@@ -0,0 +1,66 @@
+package db
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCustodyChain(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := t.Context()
+
+		capturedAt := time.Date(2025, 3, 1, 15, 4, 5, 123456789, time.FixedZone("CET", 3600))
+		for i := range 3 {
+			entry := &CustodyEntry{
+				Event:      CustodyCaptured,
+				ArticleId:  primitive.NewObjectID(),
+				SourceUrl:  "https://www.cnn.com/2025/02/26/world/video/a",
+				MediaUrl:   "https://media.cnn.com/a.mp4",
+				Path:       "downloads/a.mp4",
+				StatusCode: 200,
+				Headers:    map[string][]string{"Content-Type": {"video/mp4"}, "Etag": {"abc"}},
+				Bytes:      int64(1000 + i),
+				SHA256:     "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+				CapturedAt: capturedAt.Add(time.Duration(i) * time.Minute),
+			}
+			require.NoError(t, store.AppendCustodyEntry(ctx, entry))
+			require.Equal(t, int64(i+1), entry.Sequence)
+		}
+
+		// the hashes hold for the entries as they are stored
+		entries, err := store.FindCustodyEntries(ctx)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		require.Empty(t, VerifyCustodyChain(entries))
+		require.Empty(t, entries[0].PreviousHash)
+		require.Equal(t, entries[0].Hash, entries[1].PreviousHash)
+
+		// the event is hashed
+		disposal := &CustodyEntry{Event: CustodyDisposed, Path: "downloads/a.mp4", CapturedAt: capturedAt}
+		require.NoError(t, store.AppendCustodyEntry(ctx, disposal))
+		entries, err = store.FindCustodyEntries(ctx)
+		require.NoError(t, err)
+		require.Len(t, entries, 4)
+		require.True(t, entries[3].Disposed())
+		require.Empty(t, VerifyCustodyChain(entries))
+		captured := *entries[3]
+		captured.Event = CustodyCaptured
+		require.Equal(t, []CustodyProblem{{Sequence: 4, Problem: "was changed after it was recorded"}},
+			VerifyCustodyChain([]*CustodyEntry{entries[0], entries[1], entries[2], &captured}))
+		entries = entries[:3]
+
+		changed := *entries[1]
+		changed.Bytes++
+		require.Equal(t, []CustodyProblem{{Sequence: 2, Problem: "was changed after it was recorded"}},
+			VerifyCustodyChain([]*CustodyEntry{entries[0], &changed, entries[2]}))
+
+		require.Equal(t, []CustodyProblem{
+			{Sequence: 3, Problem: "expected sequence 2, entries are missing"},
+			{Sequence: 3, Problem: "does not link to the entry before it"},
+		}, VerifyCustodyChain(slices.Delete(entries, 1, 2)))
+	})
+}
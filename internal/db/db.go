@@ -11,11 +11,18 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Mongo stores the collections in MongoDB.
 type Mongo struct {
 	client *mongo.Client
+	name   string // Name of the database, DatabaseName when it connected
 }
 
-var Models *Mongo
+// defaultDatabase is the database of the built-in profile.
+const defaultDatabase = "sleuth"
+
+// DatabaseName is the database the collections are in, set by the profile of the command.
+// The embedded database keeps every database other than the default one in a file of its own, see boltPath.
+var DatabaseName = defaultDatabase
 
 func GetMongoURI() string {
 	uri := os.Getenv("MONGODB_URI")
@@ -25,16 +32,32 @@ func GetMongoURI() string {
 	return uri
 }
 
-func init() {
-	Models = &Mongo{}
+func (c *Mongo) database() *mongo.Database {
+	return c.client.Database(c.name)
 }
 
 func (c *Mongo) articles() *mongo.Collection {
-	return c.client.Database("sleuth").Collection("articles")
+	return c.database().Collection("articles")
 }
 
 func (c *Mongo) queries() *mongo.Collection {
-	return c.client.Database("sleuth").Collection("queries")
+	return c.database().Collection("queries")
+}
+
+func (c *Mongo) queryTemplates() *mongo.Collection {
+	return c.database().Collection("queryTemplates")
+}
+
+func (c *Mongo) schedules() *mongo.Collection {
+	return c.database().Collection("schedules")
+}
+
+func (c *Mongo) runs() *mongo.Collection {
+	return c.database().Collection("runs")
+}
+
+func (c *Mongo) migrations() *mongo.Collection {
+	return c.database().Collection("migrations")
 }
 
 func ensureUrlUniqueIndex(collection *mongo.Collection) error {
@@ -53,6 +76,11 @@ func ensureUrlUniqueIndex(collection *mongo.Collection) error {
 }
 
 func (c *Mongo) ConnectDatabase(uri string) error {
+	// long running commands connect once and reuse the client
+	if c.client != nil {
+		return nil
+	}
+
 	clientOptions := options.Client().ApplyURI(uri)
 	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
@@ -65,10 +93,17 @@ func (c *Mongo) ConnectDatabase(uri string) error {
 	}
 
 	c.client = client
+	c.name = DatabaseName
 
 	if err := ensureUrlUniqueIndex(c.articles()); err != nil {
 		return err
 	}
+	if err := ensureHistoryIndex(c.history()); err != nil {
+		return err
+	}
+	if err := ensureCustodySequenceIndex(c.custody()); err != nil {
+		return err
+	}
 	hosts := clientOptions.Hosts
 	log.Info().Strs("hosts", hosts).Msg("Connected to MongoDB!")
 	return nil
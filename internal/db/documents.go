@@ -0,0 +1,646 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Collections of the document backends. Every collection is a bucket of BSON documents keyed by
+// their ObjectID, and articleUrls maps the url of every article to its ID to keep urls unique.
+const (
+	articlesBucket       = "articles"
+	articleUrlsBucket    = "articleUrls"
+	queriesBucket        = "queries"
+	queryTemplatesBucket = "queryTemplates"
+	schedulesBucket      = "schedules"
+	runsBucket           = "runs"
+	migrationsBucket     = "migrations"
+	historyBucket        = "history"
+	custodyBucket        = "custody"
+)
+
+var documentBuckets = []string{articlesBucket, articleUrlsBucket, queriesBucket, queryTemplatesBucket, schedulesBucket, runsBucket, migrationsBucket, historyBucket, custodyBucket}
+
+// documentTx reads and writes the buckets of a document backend within a transaction.
+// Keys are iterated in byte order, which is insertion order for ObjectIDs.
+type documentTx interface {
+	get(bucket string, key []byte) []byte
+	put(bucket string, key, value []byte) error
+	delete(bucket string, key []byte) error
+	forEach(bucket string, fn func(key, value []byte) error) error
+}
+
+// documentBackend runs transactions. Changes of an update are discarded if fn returns an error.
+type documentBackend interface {
+	view(fn func(tx documentTx) error) error
+	update(fn func(tx documentTx) error) error
+}
+
+// documentStore implements Store for backends that store BSON documents in buckets,
+// following MongoDB's semantics for the operations the commands use.
+type documentStore struct {
+	backend documentBackend
+}
+
+func putDocument(tx documentTx, bucket string, id primitive.ObjectID, doc any) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return tx.put(bucket, id[:], data)
+}
+
+// getDocument decodes the document with the given ID, and returns false if there is none.
+func getDocument(tx documentTx, bucket string, id primitive.ObjectID, doc any) (bool, error) {
+	data := tx.get(bucket, id[:])
+	if data == nil {
+		return false, nil
+	}
+	return true, bson.Unmarshal(data, doc)
+}
+
+// findDocuments decodes every document of a bucket that matches the filter, in insertion order.
+func findDocuments[T any](tx documentTx, bucket string, filter bson.M) ([]*T, error) {
+	var docs []*T
+	err := tx.forEach(bucket, func(k, data []byte) error {
+		if len(filter) > 0 {
+			var m bson.M
+			if err := bson.Unmarshal(data, &m); err != nil {
+				return err
+			}
+			matched, err := matchFilter(m, filter)
+			if err != nil || !matched {
+				return err
+			}
+		}
+		var doc T
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		docs = append(docs, &doc)
+		return nil
+	})
+	return docs, err
+}
+
+// findOneDocument decodes the first document of a bucket that matches the filter, or returns nil.
+func findOneDocument[T any](tx documentTx, bucket string, filter bson.M) (*T, error) {
+	docs, err := findDocuments[T](tx, bucket, filter)
+	if err != nil || len(docs) == 0 {
+		return nil, err
+	}
+	return docs[0], nil
+}
+
+// modifyDocument decodes the document with the given ID, lets modify change it and stores it again.
+// It returns false if there is no such document.
+func modifyDocument[T any](tx documentTx, bucket string, id primitive.ObjectID, modify func(doc *T) error) (bool, error) {
+	var doc T
+	found, err := getDocument(tx, bucket, id, &doc)
+	if err != nil || !found {
+		return found, err
+	}
+	if err := modify(&doc); err != nil {
+		return true, err
+	}
+	return true, putDocument(tx, bucket, id, &doc)
+}
+
+// setDocumentFields sets the fields of the document with the given ID, like MongoDB's $set.
+// It returns false if there is no such document.
+func setDocumentFields(tx documentTx, bucket string, id primitive.ObjectID, update bson.M) (bool, error) {
+	return modifyDocument(tx, bucket, id, func(doc *bson.M) error {
+		for path, value := range update {
+			if err := setPath(*doc, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateArticle inserts a new article and assigns its ID to article.Id, unless it already has one.
+// It returns ErrDuplicateUrl if an article with the same url is already stored.
+func (s *documentStore) CreateArticle(ctx context.Context, article *Article) error {
+	return s.backend.update(func(tx documentTx) error {
+		if tx.get(articleUrlsBucket, []byte(article.Url)) != nil {
+			return ErrDuplicateUrl
+		}
+		if article.SchemaVersion == 0 {
+			article.SchemaVersion = CurrentSchemaVersion()
+		}
+		given, id := article.Id, article.Id
+		if id.IsZero() {
+			id = primitive.NewObjectID()
+		} else if tx.get(articlesBucket, id[:]) != nil {
+			return fmt.Errorf("an article with ID %s already exists", id.Hex())
+		}
+		article.Id = id
+		if err := putDocument(tx, articlesBucket, id, article); err != nil {
+			article.Id = given
+			return err
+		}
+		return tx.put(articleUrlsBucket, []byte(article.Url), id[:])
+	})
+}
+
+// articleIdByUrl returns the ID of the article with the given url.
+func articleIdByUrl(tx documentTx, url string) (primitive.ObjectID, bool) {
+	var id primitive.ObjectID
+	data := tx.get(articleUrlsBucket, []byte(url))
+	if data == nil {
+		return id, false
+	}
+	copy(id[:], data)
+	return id, true
+}
+
+func (s *documentStore) FindArticleByUrl(ctx context.Context, url string) (*Article, error) {
+	var article Article
+	err := s.backend.view(func(tx documentTx) error {
+		id, found := articleIdByUrl(tx, url)
+		if found {
+			found, err := getDocument(tx, articlesBucket, id, &article)
+			if err != nil || found {
+				return err
+			}
+		}
+		return ErrArticleNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &article, nil
+}
+
+func (s *documentStore) FindArticleByID(ctx context.Context, id primitive.ObjectID) (*Article, error) {
+	var article Article
+	err := s.backend.view(func(tx documentTx) error {
+		found, err := getDocument(tx, articlesBucket, id, &article)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrArticleNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &article, nil
+}
+
+func (s *documentStore) AddArticleProvenance(ctx context.Context, url string, entry SearchProvenance) error {
+	return s.backend.update(func(tx documentTx) error {
+		id, found := articleIdByUrl(tx, url)
+		if found {
+			found, err := modifyDocument(tx, articlesBucket, id, func(article *Article) error {
+				article.Provenance = append(article.Provenance, entry)
+				return nil
+			})
+			if err != nil || found {
+				return err
+			}
+		}
+		return errors.New("no article found to update")
+	})
+}
+
+func (s *documentStore) FindAllArticles(ctx context.Context) ([]*Article, error) {
+	return s.FindArticlesByFilter(ctx, bson.M{})
+}
+
+func (s *documentStore) FindAllArticlesNotChecked(ctx context.Context) ([]*Article, error) {
+	return s.FindArticlesByFilter(ctx, bson.M{"aiHasCheckedIfShouldDownloadVideo": false})
+}
+
+// UpdateArticle sets the fields of an article. Changing the url keeps urls unique.
+func (s *documentStore) UpdateArticle(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	return s.backend.update(func(tx documentTx) error {
+		var article Article
+		found, err := getDocument(tx, articlesBucket, id, &article)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrArticleNotFound
+		}
+		if url, found := update["url"].(string); found && url != article.Url {
+			if tx.get(articleUrlsBucket, []byte(url)) != nil {
+				return ErrDuplicateUrl
+			}
+			if err := tx.delete(articleUrlsBucket, []byte(article.Url)); err != nil {
+				return err
+			}
+			if err := tx.put(articleUrlsBucket, []byte(url), id[:]); err != nil {
+				return err
+			}
+		}
+		if err := recordArticleChange(ctx, tx, id, update, time.Now()); err != nil {
+			return err
+		}
+		_, err = setDocumentFields(tx, articlesBucket, id, update)
+		return err
+	})
+}
+
+func (s *documentStore) FindArticlesByFilter(ctx context.Context, filter bson.M) ([]*Article, error) {
+	var articles []*Article
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		articles, err = findDocuments[Article](tx, articlesBucket, filter)
+		return err
+	})
+	return articles, err
+}
+
+// UpdateArticles sets fields on every article that matches the filter. The url cannot be changed this way.
+func (s *documentStore) UpdateArticles(ctx context.Context, filter bson.M, update bson.M) (int64, error) {
+	if _, found := update["url"]; found {
+		return 0, errors.New("the url of several articles cannot be set at once")
+	}
+	var matched int64
+	err := s.backend.update(func(tx documentTx) error {
+		articles, err := findDocuments[Article](tx, articlesBucket, filter)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, article := range articles {
+			if err := recordArticleChange(ctx, tx, article.Id, update, now); err != nil {
+				return err
+			}
+			if _, err := setDocumentFields(tx, articlesBucket, article.Id, update); err != nil {
+				return err
+			}
+			matched++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return matched, nil
+}
+
+// DeleteArticle removes an article, frees its url and records the deletion in the history bucket.
+func (s *documentStore) DeleteArticle(ctx context.Context, id primitive.ObjectID) error {
+	return s.backend.update(func(tx documentTx) error {
+		var article Article
+		found, err := getDocument(tx, articlesBucket, id, &article)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("no article found to delete")
+		}
+		deletion := newArticleDeletion(ctx, tx.get(articlesBucket, id[:]), time.Now())
+		deletion.Id = primitive.NewObjectID()
+		if err := putDocument(tx, historyBucket, deletion.Id, deletion); err != nil {
+			return err
+		}
+		if err := tx.delete(articleUrlsBucket, []byte(article.Url)); err != nil {
+			return err
+		}
+		return tx.delete(articlesBucket, id[:])
+	})
+}
+
+func (s *documentStore) CreateQuery(ctx context.Context, query *Query) error {
+	if query.CreatedAt.IsZero() {
+		query.CreatedAt = time.Now()
+	}
+	return s.backend.update(func(tx documentTx) error {
+		query.Id = primitive.NewObjectID()
+		return putDocument(tx, queriesBucket, query.Id, query)
+	})
+}
+
+func (s *documentStore) FindQueryByValue(ctx context.Context, queryString string) (*Query, error) {
+	var query *Query
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		query, err = findOneDocument[Query](tx, queriesBucket, bson.M{"query": queryString})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return nil, errors.New("query not found")
+	}
+	return query, nil
+}
+
+func (s *documentStore) FindAllQueries(ctx context.Context) ([]*Query, error) {
+	var queries []*Query
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		queries, err = findDocuments[Query](tx, queriesBucket, nil)
+		return err
+	})
+	return queries, err
+}
+
+func (s *documentStore) FindUnusedQueries(ctx context.Context) ([]*Query, error) {
+	var queries []*Query
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		queries, err = findDocuments[Query](tx, queriesBucket, bson.M{"used": false})
+		return err
+	})
+	return queries, err
+}
+
+func (s *documentStore) UpdateQuery(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	return s.backend.update(func(tx documentTx) error {
+		found, err := setDocumentFields(tx, queriesBucket, id, update)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("no query found to update")
+		}
+		return nil
+	})
+}
+
+func (s *documentStore) RecordQueryRun(ctx context.Context, id primitive.ObjectID, run QueryRun) error {
+	return s.backend.update(func(tx documentTx) error {
+		found, err := modifyDocument(tx, queriesBucket, id, func(query *Query) error {
+			query.Used = true
+			query.LastRunAt = run.RanAt
+			query.Runs = append(query.Runs, run)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("no query found to update")
+		}
+		return nil
+	})
+}
+
+func (s *documentStore) CreateQueryTemplate(ctx context.Context, template *QueryTemplate) error {
+	if template.CreatedAt.IsZero() {
+		template.CreatedAt = time.Now()
+	}
+	return s.backend.update(func(tx documentTx) error {
+		template.Id = primitive.NewObjectID()
+		return putDocument(tx, queryTemplatesBucket, template.Id, template)
+	})
+}
+
+func (s *documentStore) FindQueryTemplateByValue(ctx context.Context, templateString string) (*QueryTemplate, error) {
+	var template *QueryTemplate
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		template, err = findOneDocument[QueryTemplate](tx, queryTemplatesBucket, bson.M{"template": templateString})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, errors.New("query template not found")
+	}
+	return template, nil
+}
+
+func (s *documentStore) UpdateQueryTemplate(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	return s.backend.update(func(tx documentTx) error {
+		found, err := setDocumentFields(tx, queryTemplatesBucket, id, update)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("no query template found to update")
+		}
+		return nil
+	})
+}
+
+func (s *documentStore) CreateSchedule(ctx context.Context, schedule *Schedule) error {
+	if schedule.CreatedAt.IsZero() {
+		schedule.CreatedAt = time.Now()
+	}
+	return s.backend.update(func(tx documentTx) error {
+		schedule.Id = primitive.NewObjectID()
+		return putDocument(tx, schedulesBucket, schedule.Id, schedule)
+	})
+}
+
+func (s *documentStore) FindScheduleByName(ctx context.Context, name string) (*Schedule, error) {
+	var schedule *Schedule
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		schedule, err = findOneDocument[Schedule](tx, schedulesBucket, bson.M{"name": name})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, errors.New("schedule not found")
+	}
+	return schedule, nil
+}
+
+func (s *documentStore) FindAllSchedules(ctx context.Context) ([]*Schedule, error) {
+	var schedules []*Schedule
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		schedules, err = findDocuments[Schedule](tx, schedulesBucket, nil)
+		return err
+	})
+	return schedules, err
+}
+
+func (s *documentStore) DeleteScheduleByName(ctx context.Context, name string) error {
+	return s.backend.update(func(tx documentTx) error {
+		schedule, err := findOneDocument[Schedule](tx, schedulesBucket, bson.M{"name": name})
+		if err != nil {
+			return err
+		}
+		if schedule == nil {
+			return errors.New("no schedule found to delete")
+		}
+		return tx.delete(schedulesBucket, schedule.Id[:])
+	})
+}
+
+// ClaimScheduleRun moves a due schedule on to its next run if it is still due at dueAt.
+// Write transactions are serialized, so exactly one caller gets claimed == true.
+func (s *documentStore) ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, dueAt, nextRunAt time.Time) (claimed bool, err error) {
+	err = s.backend.update(func(tx documentTx) error {
+		_, err := modifyDocument(tx, schedulesBucket, id, func(schedule *Schedule) error {
+			if !schedule.NextRunAt.Equal(dueAt) {
+				return nil
+			}
+			schedule.NextRunAt = nextRunAt
+			schedule.LastRunAt = time.Now()
+			claimed = true
+			return nil
+		})
+		return err
+	})
+	return claimed, err
+}
+
+func (s *documentStore) RecordScheduleResult(ctx context.Context, id primitive.ObjectID, result ScheduleResult) error {
+	return s.backend.update(func(tx documentTx) error {
+		found, err := modifyDocument(tx, schedulesBucket, id, func(schedule *Schedule) error {
+			schedule.LastResult = &result
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("no schedule found to update")
+		}
+		return nil
+	})
+}
+
+func (s *documentStore) CreateRun(ctx context.Context, run *SearchRun) error {
+	if run.StartedAt.IsZero() {
+		run.StartedAt = time.Now()
+	}
+	return s.backend.update(func(tx documentTx) error {
+		run.Id = primitive.NewObjectID()
+		return putDocument(tx, runsBucket, run.Id, run)
+	})
+}
+
+// modifyRun changes the run with the given ID in its own transaction.
+func (s *documentStore) modifyRun(id primitive.ObjectID, modify func(run *SearchRun)) error {
+	return s.backend.update(func(tx documentTx) error {
+		found, err := modifyDocument(tx, runsBucket, id, func(run *SearchRun) error {
+			modify(run)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("no run found to update")
+		}
+		return nil
+	})
+}
+
+func (s *documentStore) AddRunSearches(ctx context.Context, id primitive.ObjectID, searches []RunSearch) error {
+	return s.modifyRun(id, func(run *SearchRun) {
+		for _, s := range searches {
+			run.Searches = append(run.Searches, s)
+			run.NewArticles += s.NewArticles
+			run.Duplicates += s.Duplicates
+			if s.Error != "" {
+				run.Errors = append(run.Errors, s.Provider+": "+s.Error)
+			}
+		}
+	})
+}
+
+func (s *documentStore) SaveRunCheckpoint(ctx context.Context, id primitive.ObjectID, checkpoint RunCheckpoint) error {
+	if checkpoint.UpdatedAt.IsZero() {
+		checkpoint.UpdatedAt = time.Now()
+	}
+	return s.modifyRun(id, func(run *SearchRun) {
+		if run.Checkpoints == nil {
+			run.Checkpoints = make(map[string]RunCheckpoint)
+		}
+		run.Checkpoints[CheckpointKey(checkpoint.Provider, checkpoint.QueryId)] = checkpoint
+	})
+}
+
+func (s *documentStore) ResumeRun(ctx context.Context, id primitive.ObjectID, resumedAt time.Time) error {
+	return s.modifyRun(id, func(run *SearchRun) {
+		run.FinishedAt = time.Time{}
+		run.ResumedAt = append(run.ResumedAt, resumedAt)
+	})
+}
+
+func (s *documentStore) FinishRun(ctx context.Context, id primitive.ObjectID, finishedAt time.Time, runErrors []string) error {
+	return s.modifyRun(id, func(run *SearchRun) {
+		run.FinishedAt = finishedAt
+		run.Errors = append(run.Errors, runErrors...)
+	})
+}
+
+func (s *documentStore) FindRunByID(ctx context.Context, id primitive.ObjectID) (*SearchRun, error) {
+	var run SearchRun
+	err := s.backend.view(func(tx documentTx) error {
+		found, err := getDocument(tx, runsBucket, id, &run)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("run not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (s *documentStore) FindRecentRuns(ctx context.Context, limit int64) ([]*SearchRun, error) {
+	var runs []*SearchRun
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		runs, err = findDocuments[SearchRun](tx, runsBucket, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	if limit > 0 && int64(len(runs)) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+func (s *documentStore) FindMigrations(ctx context.Context) ([]*MigrationRecord, error) {
+	var records []*MigrationRecord
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		records, err = findDocuments[MigrationRecord](tx, migrationsBucket, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+	return records, nil
+}
+
+func (s *documentStore) RecordMigration(ctx context.Context, record *MigrationRecord) error {
+	return s.backend.update(func(tx documentTx) error {
+		record.Id = primitive.NewObjectID()
+		return putDocument(tx, migrationsBucket, record.Id, record)
+	})
+}
+
+// ensure that every backend implements the Store interface
+var (
+	_ Store = &Mongo{}
+	_ Store = &Bolt{}
+	_ Store = &Memory{}
+)
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// testStores runs a test against every backend that does not need a server.
+func testStores(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("bolt", func(t *testing.T) {
+		store, err := OpenBolt(filepath.Join(t.TempDir(), "sleuth.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		test(t, store)
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+}
+
+func TestBoltPathOfProfile(t *testing.T) {
+	t.Cleanup(func() { DatabaseName = defaultDatabase })
+	require.Equal(t, "data/sleuth.db", boltPath("data/sleuth.db"))
+	DatabaseName = "sleuth-wildfires"
+	require.Equal(t, filepath.Join("data", "sleuth-wildfires.db"), boltPath("data/sleuth.db"))
+	require.Equal(t, "sleuth-wildfires", boltPath("store"))
+	require.Empty(t, boltPath(""))
+
+	// a profile does not see the articles of the default database
+	uri := "bolt://" + filepath.Join(t.TempDir(), "sleuth.db")
+	connect := func(database string) Store {
+		DatabaseName, Models = database, nil
+		require.NoError(t, Connect(uri))
+		store := Models
+		Models = nil
+		t.Cleanup(func() { store.(*Bolt).Close() })
+		return store
+	}
+	require.NoError(t, connect(defaultDatabase).CreateArticle(t.Context(), &Article{Url: "https://www.cnn.com/2025/02/26/world/video/a"}))
+	articles, err := connect("sleuth-wildfires").FindArticlesByFilter(t.Context(), bson.M{})
+	require.NoError(t, err)
+	require.Empty(t, articles)
+}
+
+func TestStoreArticles(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := t.Context()
+
+		article := &Article{Url: "https://www.cnn.com/2025/02/26/world/video/a", Title: "A", Provider: "cnn"}
+		require.NoError(t, store.CreateArticle(ctx, article))
+		require.False(t, article.Id.IsZero())
+
+		err := store.CreateArticle(ctx, &Article{Url: article.Url})
+		require.True(t, IsDuplicateKeyError(err))
+
+		require.NoError(t, store.AddArticleProvenance(ctx, article.Url, SearchProvenance{Query: "body found", Provider: "cnn", Page: 1, Rank: 2}))
+		require.NoError(t, store.UpdateArticle(ctx, article.Id, bson.M{"aiHasCheckedIfShouldDownloadVideo": true, "aiSuggestsDownloadingVideo": true}))
+
+		found, err := store.FindArticleByUrl(ctx, article.Url)
+		require.NoError(t, err)
+		require.Equal(t, "A", found.Title)
+		require.True(t, found.AiSuggestsDownloadingVideo)
+		require.Len(t, found.Provenance, 1)
+		require.Equal(t, 2, found.Provenance[0].Rank)
+
+		_, err = store.FindArticleByUrl(ctx, "https://www.cnn.com/missing")
+		require.Error(t, err)
+
+		require.NoError(t, store.CreateArticle(ctx, &Article{Url: "https://www.cnn.com/2025/02/26/world/video/b", Provider: "cnn"}))
+		require.True(t, IsDuplicateKeyError(store.UpdateArticle(ctx, article.Id, bson.M{"url": "https://www.cnn.com/2025/02/26/world/video/b"})))
+
+		// a failed update leaves the article and its url untouched
+		require.Error(t, store.UpdateArticle(ctx, article.Id, bson.M{"url": "https://www.cnn.com/2025/02/26/world/video/c", "title.text": "C"}))
+		_, err = store.FindArticleByUrl(ctx, article.Url)
+		require.NoError(t, err)
+		_, err = store.FindArticleByUrl(ctx, "https://www.cnn.com/2025/02/26/world/video/c")
+		require.Error(t, err)
+
+		notChecked, err := store.FindAllArticlesNotChecked(ctx)
+		require.NoError(t, err)
+		require.Len(t, notChecked, 1)
+		require.Equal(t, "https://www.cnn.com/2025/02/26/world/video/b", notChecked[0].Url)
+
+		withProvenance, err := store.FindArticlesByFilter(ctx, bson.M{"provenance": bson.M{"$exists": true}})
+		require.NoError(t, err)
+		require.Len(t, withProvenance, 1)
+
+		notCrawled, err := store.FindArticlesByFilter(ctx, bson.M{"aiSuggestsDownloadingVideo": true, "relatedCrawled": bson.M{"$ne": true}})
+		require.NoError(t, err)
+		require.Len(t, notCrawled, 1)
+
+		_, err = store.FindArticlesByFilter(ctx, bson.M{"title": bson.M{"$regex": "A"}})
+		require.Error(t, err)
+
+		// deleting an article frees its url
+		require.NoError(t, store.DeleteArticle(ctx, article.Id))
+		_, err = store.FindArticleByID(ctx, article.Id)
+		require.Error(t, err)
+		require.Error(t, store.DeleteArticle(ctx, article.Id))
+		require.NoError(t, store.CreateArticle(ctx, &Article{Url: article.Url}))
+	})
+}
+
+func TestStoreQueriesAndRuns(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := t.Context()
+
+		query := &Query{Query: "body found in lake"}
+		require.NoError(t, store.CreateQuery(ctx, query))
+		require.NoError(t, store.RecordQueryRun(ctx, query.Id, QueryRun{RanAt: time.Now(), NewArticles: 3}))
+		unused, err := store.FindUnusedQueries(ctx)
+		require.NoError(t, err)
+		require.Empty(t, unused)
+		found, err := store.FindQueryByValue(ctx, "body found in lake")
+		require.NoError(t, err)
+		require.True(t, found.Used)
+		require.Len(t, found.Runs, 1)
+
+		run := &SearchRun{Queries: []string{query.Query}, Providers: []string{"cnn"}}
+		require.NoError(t, store.CreateRun(ctx, run))
+		require.NoError(t, store.AddRunSearches(ctx, run.Id, []RunSearch{{Provider: "cnn", Query: query.Query, NewArticles: 2}, {Provider: "foxnews", Query: query.Query, Error: "timeout"}}))
+		require.NoError(t, store.SaveRunCheckpoint(ctx, run.Id, RunCheckpoint{Query: query.Query, QueryId: query.Id, Provider: "cnn", Page: 2}))
+		require.NoError(t, store.FinishRun(ctx, run.Id, time.Now(), nil))
+
+		stored, err := store.FindRunByID(ctx, run.Id)
+		require.NoError(t, err)
+		require.Equal(t, 2, stored.NewArticles)
+		require.Equal(t, []string{"foxnews: timeout"}, stored.Errors)
+		require.Equal(t, 2, stored.Checkpoints[CheckpointKey("cnn", query.Id)].Page)
+		require.False(t, stored.FinishedAt.IsZero())
+	})
+}
+
+func TestStoreClaimScheduleRun(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := t.Context()
+
+		due := time.Now().Truncate(time.Millisecond)
+		schedule := &Schedule{Name: "lakes", Cron: "0 * * * *", NextRunAt: due}
+		require.NoError(t, store.CreateSchedule(ctx, schedule))
+
+		claimed, err := store.ClaimScheduleRun(ctx, schedule.Id, due, due.Add(time.Hour))
+		require.NoError(t, err)
+		require.True(t, claimed)
+		claimed, err = store.ClaimScheduleRun(ctx, schedule.Id, due, due.Add(time.Hour))
+		require.NoError(t, err)
+		require.False(t, claimed)
+
+		require.NoError(t, store.DeleteScheduleByName(ctx, "lakes"))
+		require.Error(t, store.DeleteScheduleByName(ctx, "lakes"))
+	})
+}
+
+func TestStoreScanArticles(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := t.Context()
+
+		for i := range 5 {
+			article := &Article{Url: fmt.Sprintf("https://www.cnn.com/video/%d", i), Title: fmt.Sprint(i), Provider: "cnn", Embed: []float64{0.1, 0.2}}
+			require.NoError(t, store.CreateArticle(ctx, article))
+		}
+
+		count, err := store.CountArticles(ctx, bson.M{"aiHasCheckedIfShouldDownloadVideo": false})
+		require.NoError(t, err)
+		require.Equal(t, int64(5), count)
+
+		// the store can be written to while scanning, and every article is yielded once
+		var titles []string
+		filter := bson.M{"aiHasCheckedIfShouldDownloadVideo": false}
+		for article, err := range store.ScanArticles(ctx, filter, ScanOptions{BatchSize: 2, Projection: WithoutEmbeddings}) {
+			require.NoError(t, err)
+			require.Empty(t, article.Embed)
+			require.Equal(t, "cnn", article.Provider)
+			titles = append(titles, article.Title)
+			require.NoError(t, store.UpdateArticle(ctx, article.Id, bson.M{"aiHasCheckedIfShouldDownloadVideo": true}))
+		}
+		require.Equal(t, []string{"0", "1", "2", "3", "4"}, titles)
+
+		titles = nil
+		for article, err := range store.ScanArticles(ctx, bson.M{}, ScanOptions{NewestFirst: true, Limit: 2}) {
+			require.NoError(t, err)
+			titles = append(titles, article.Title)
+		}
+		require.Equal(t, []string{"4", "3"}, titles)
+
+		count, err = store.CountArticles(ctx, filter)
+		require.NoError(t, err)
+		require.Zero(t, count)
+
+		for doc, err := range store.ScanArticleDocuments(ctx, bson.M{}, ScanOptions{Projection: bson.M{"title": 1, "_id": 0}}) {
+			require.NoError(t, err)
+			require.Len(t, doc, 1)
+			require.Contains(t, doc, "title")
+			break
+		}
+	})
+}
+
+func TestMatchFilter(t *testing.T) {
+	doc := bson.M{
+		"provider":    "cnn",
+		"caseId":      int32(4),
+		"victimNames": bson.A{"Jane Doe"},
+		"checkpoints": bson.M{"cnn:1": bson.M{"page": int32(3)}},
+	}
+	cases := []struct {
+		filter bson.M
+		want   bool
+	}{
+		{bson.M{}, true},
+		{bson.M{"provider": "cnn"}, true},
+		{bson.M{"provider": "foxnews"}, false},
+		{bson.M{"caseId": 4}, true},
+		{bson.M{"victimNames": "Jane Doe"}, true},
+		{bson.M{"victimNames": []string{"Jane Doe"}}, true},
+		{bson.M{"location": bson.M{"$exists": false}}, true},
+		{bson.M{"location": nil}, true},
+		{bson.M{"provider": bson.M{"$ne": "cnn"}}, false},
+		{bson.M{"relatedCrawled": bson.M{"$ne": true}}, true},
+		{bson.M{"provider": bson.M{"$in": []string{"foxnews", "cnn"}}}, true},
+		{bson.M{"provider": bson.M{"$nin": []string{"foxnews", "cnn"}}}, false},
+		{bson.M{"checkpoints.cnn:1.page": 3}, true},
+	}
+	for _, c := range cases {
+		matched, err := matchFilter(doc, c.filter)
+		require.NoError(t, err)
+		require.Equal(t, c.want, matched, "%v", c.filter)
+	}
+}
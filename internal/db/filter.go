@@ -0,0 +1,256 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// matchFilter reports whether a document matches a MongoDB filter, for backends that do not
+// understand MongoDB's query language. It supports equality, which also matches an element
+// of an array field, and the $exists, $ne, $eq, $in, $nin, $lt, $lte, $gt and $gte operators.
+// Keys may be dotted paths.
+func matchFilter(doc bson.M, filter bson.M) (bool, error) {
+	for key, condition := range filter {
+		value, exists := lookupPath(doc, key)
+		operators, isOperators := operatorDocument(condition)
+		if !isOperators {
+			if !matchEqual(value, exists, condition) {
+				return false, nil
+			}
+			continue
+		}
+		for op, operand := range operators {
+			matched, err := matchOperator(value, exists, op, operand)
+			if err != nil {
+				return false, fmt.Errorf("filter on %s: %w", key, err)
+			}
+			if !matched {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// operatorDocument returns the condition as a map of operators if every key of it is an operator.
+func operatorDocument(condition any) (bson.M, bool) {
+	var operators bson.M
+	switch c := condition.(type) {
+	case bson.M:
+		operators = c
+	case map[string]any:
+		operators = c
+	default:
+		return nil, false
+	}
+	if len(operators) == 0 {
+		return nil, false
+	}
+	for op := range operators {
+		if !strings.HasPrefix(op, "$") {
+			return nil, false
+		}
+	}
+	return operators, true
+}
+
+func matchOperator(value any, exists bool, op string, operand any) (bool, error) {
+	switch op {
+	case "$exists":
+		want, ok := operand.(bool)
+		if !ok {
+			return false, fmt.Errorf("$exists needs a bool, got %T", operand)
+		}
+		return exists == want, nil
+	case "$eq":
+		return matchEqual(value, exists, operand), nil
+	case "$ne":
+		return !matchEqual(value, exists, operand), nil
+	case "$in", "$nin":
+		candidates, ok := asSlice(operand)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array, got %T", op, operand)
+		}
+		in := false
+		for _, candidate := range candidates {
+			if matchEqual(value, exists, candidate) {
+				in = true
+				break
+			}
+		}
+		return in == (op == "$in"), nil
+	case "$lt", "$lte", "$gt", "$gte":
+		bound, ok := normalizeValue(operand).(float64)
+		if !ok {
+			return false, fmt.Errorf("%s needs a number, got %T", op, operand)
+		}
+		number, ok := normalizeValue(value).(float64)
+		if !exists || !ok {
+			return false, nil
+		}
+		switch op {
+		case "$lt":
+			return number < bound, nil
+		case "$lte":
+			return number <= bound, nil
+		case "$gt":
+			return number > bound, nil
+		default:
+			return number >= bound, nil
+		}
+	default:
+		return false, fmt.Errorf("unsupported operator %s", op)
+	}
+}
+
+// matchEqual follows MongoDB's equality: null matches a missing field, and a value matches
+// an array field that contains it.
+func matchEqual(value any, exists bool, want any) bool {
+	if want == nil {
+		return !exists || value == nil
+	}
+	if !exists {
+		return false
+	}
+	if valuesEqual(value, want) {
+		return true
+	}
+	if elements, ok := asSlice(value); ok {
+		if _, wantSlice := asSlice(want); !wantSlice {
+			for _, element := range elements {
+				if valuesEqual(element, want) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func valuesEqual(a, b any) bool {
+	return reflect.DeepEqual(normalizeValue(a), normalizeValue(b))
+}
+
+// normalizeValue converts values to the type they have after a round trip through BSON,
+// so that numbers and times compare equal whatever Go type they were given as.
+func normalizeValue(v any) any {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return primitive.NewDateTimeFromTime(v)
+	case []string:
+		normalized := make([]any, len(v))
+		for i, s := range v {
+			normalized[i] = s
+		}
+		return normalized
+	case bson.A:
+		normalized := make([]any, len(v))
+		for i, e := range v {
+			normalized[i] = normalizeValue(e)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(v))
+		for i, e := range v {
+			normalized[i] = normalizeValue(e)
+		}
+		return normalized
+	default:
+		return v
+	}
+}
+
+func asSlice(v any) ([]any, bool) {
+	switch v := v.(type) {
+	case bson.A:
+		return v, true
+	case []any:
+		return v, true
+	case []string:
+		slice := make([]any, len(v))
+		for i, s := range v {
+			slice[i] = s
+		}
+		return slice, true
+	case []primitive.ObjectID:
+		slice := make([]any, len(v))
+		for i, id := range v {
+			slice[i] = id
+		}
+		return slice, true
+	default:
+		return nil, false
+	}
+}
+
+// lookupPath returns the value at a dotted path of a document.
+func lookupPath(doc bson.M, path string) (any, bool) {
+	var current any = doc
+	for _, key := range strings.Split(path, ".") {
+		switch c := current.(type) {
+		case bson.M:
+			value, found := c[key]
+			if !found {
+				return nil, false
+			}
+			current = value
+		case primitive.D:
+			value, found := documentToMap(c)[key]
+			if !found {
+				return nil, false
+			}
+			current = value
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setPath sets the value at a dotted path of a document, creating the documents on the way.
+func setPath(doc bson.M, path string, value any) error {
+	keys := strings.Split(path, ".")
+	current := doc
+	for _, key := range keys[:len(keys)-1] {
+		next, found := current[key]
+		if !found || next == nil {
+			child := bson.M{}
+			current[key] = child
+			current = child
+			continue
+		}
+		switch n := next.(type) {
+		case bson.M:
+			current = n
+		case primitive.D:
+			child := documentToMap(n)
+			current[key] = child
+			current = child
+		default:
+			return fmt.Errorf("cannot set %s: %s is not a document", path, key)
+		}
+	}
+	current[keys[len(keys)-1]] = value
+	return nil
+}
+
+func documentToMap(d primitive.D) bson.M {
+	m := make(bson.M, len(d))
+	for _, e := range d {
+		m[e.Key] = e.Value
+	}
+	return m
+}
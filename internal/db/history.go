@@ -0,0 +1,269 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Actor is who changes articles: the command that changes them and the person who ran it.
+type Actor struct {
+	Command  string
+	Operator string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context that records actor with every change of an article made with it.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor of a context, or an unknown actor if there is none.
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Command: "unknown", Operator: "unknown"}
+}
+
+// FieldChange is the value of a field before and after a change, as stored.
+// A value is zero if the field was not set.
+type FieldChange struct {
+	Field string        `bson:"field"`
+	Old   bson.RawValue `bson:"old,omitempty"`
+	New   bson.RawValue `bson:"new,omitempty"`
+}
+
+// FormatValue formats a stored value as relaxed MongoDB Extended JSON, or null if it is not set.
+func FormatValue(value bson.RawValue) string {
+	if value.IsZero() {
+		return "null"
+	}
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: value}}, false, false)
+	if err != nil {
+		return value.String()
+	}
+	var wrapper struct {
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return value.String()
+	}
+	return string(wrapper.V)
+}
+
+// MarshalJSON writes the values of the change as they are formatted by FormatValue.
+func (c FieldChange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field string          `json:"field"`
+		Old   json.RawMessage `json:"old"`
+		New   json.RawMessage `json:"new"`
+	}{c.Field, json.RawMessage(FormatValue(c.Old)), json.RawMessage(FormatValue(c.New))})
+}
+
+// ArticleChange records an update or the deletion of an article in the history collection. Updating the
+// stage progress and search provenance of an article is not recorded, as they keep their own history.
+type ArticleChange struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ArticleId primitive.ObjectID `bson:"articleId" json:"articleId"`
+	Changes   []FieldChange      `bson:"changes" json:"changes"`
+	Deleted   bool               `bson:"deleted,omitempty" json:"deleted,omitempty"` // The article was deleted; the changes hold its url
+	Command   string             `bson:"command" json:"command"`                     // Command that made the change, e.g. "sleuth article set"
+	Operator  string             `bson:"operator" json:"operator"`                   // Who ran the command
+	ChangedAt time.Time          `bson:"changedAt" json:"changedAt"`
+}
+
+// newArticleChange compares the fields an update sets with their stored values, and returns
+// the change of the article, or nil if the update does not change any of them.
+func newArticleChange(ctx context.Context, stored bson.Raw, update bson.M, changedAt time.Time) (*ArticleChange, error) {
+	id, _ := stored.Lookup("_id").ObjectIDOK()
+	actor := ActorFrom(ctx)
+	change := &ArticleChange{
+		ArticleId: id,
+		Command:   actor.Command,
+		Operator:  actor.Operator,
+		ChangedAt: changedAt,
+	}
+
+	// the new values are marshaled like the update stores them, to compare them with the stored ones
+	updated := make(bson.D, 0, len(update))
+	for path, value := range update {
+		updated = append(updated, bson.E{Key: path, Value: value})
+	}
+	data, err := bson.Marshal(updated)
+	if err != nil {
+		return nil, err
+	}
+	values, err := bson.Raw(data).Elements()
+	if err != nil {
+		return nil, err
+	}
+	for _, element := range values {
+		path := element.Key()
+		old, err := stored.LookupErr(strings.Split(path, ".")...)
+		if err != nil {
+			old = bson.RawValue{}
+		}
+		if old.Equal(element.Value()) {
+			continue
+		}
+		change.Changes = append(change.Changes, FieldChange{Field: path, Old: old, New: element.Value()})
+	}
+	if len(change.Changes) == 0 {
+		return nil, nil
+	}
+	sort.Slice(change.Changes, func(i, j int) bool { return change.Changes[i].Field < change.Changes[j].Field })
+	return change, nil
+}
+
+// newArticleDeletion returns the change that records the deletion of the stored article.
+func newArticleDeletion(ctx context.Context, stored bson.Raw, deletedAt time.Time) *ArticleChange {
+	id, _ := stored.Lookup("_id").ObjectIDOK()
+	actor := ActorFrom(ctx)
+	return &ArticleChange{
+		ArticleId: id,
+		Changes:   []FieldChange{{Field: "url", Old: stored.Lookup("url")}},
+		Deleted:   true,
+		Command:   actor.Command,
+		Operator:  actor.Operator,
+		ChangedAt: deletedAt,
+	}
+}
+
+func (c *Mongo) history() *mongo.Collection {
+	return c.database().Collection("history")
+}
+
+func ensureHistoryIndex(collection *mongo.Collection) error {
+	indexModel := mongo.IndexModel{Keys: bson.D{{Key: "articleId", Value: 1}, {Key: "changedAt", Value: 1}}}
+	if _, err := collection.Indexes().CreateOne(context.Background(), indexModel); err != nil {
+		return fmt.Errorf("failed to create history index: %w", err)
+	}
+	return nil
+}
+
+// updateProjection selects the stored values of the fields an update sets.
+func updateProjection(update bson.M) bson.M {
+	projection := bson.M{}
+	for path := range update {
+		projection[path] = 1
+	}
+	return projection
+}
+
+// UpdateArticle sets the fields of an article and records the values it changed in the history collection.
+func (c *Mongo) UpdateArticle(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	// Set a timeout for the operation.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// The values before the update are returned by the same operation that updates them.
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before).SetProjection(updateProjection(update))
+	stored, err := c.articles().FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": update}, opts).Raw()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrArticleNotFound
+		}
+		return err
+	}
+
+	change, err := newArticleChange(ctx, stored, update, time.Now())
+	if err != nil || change == nil {
+		return err
+	}
+	_, err = c.history().InsertOne(ctx, change)
+	return err
+}
+
+// UpdateArticles sets the fields of every article that matches the filter and returns how many matched.
+// The values it changed are recorded in the history collection.
+func (c *Mongo) UpdateArticles(ctx context.Context, filter bson.M, update bson.M) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cursor, err := c.articles().Find(ctx, filter, options.Find().SetProjection(updateProjection(update)))
+	if err != nil {
+		return 0, err
+	}
+	var stored []bson.Raw
+	for cursor.Next(ctx) {
+		// Current is only valid until the next call to Next
+		stored = append(stored, bson.Raw(bytes.Clone(cursor.Current)))
+	}
+	cursor.Close(ctx)
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+
+	result, err := c.articles().UpdateMany(ctx, filter, bson.M{"$set": update})
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var changes []any
+	for _, doc := range stored {
+		change, err := newArticleChange(ctx, doc, update, now)
+		if err != nil {
+			return result.MatchedCount, err
+		}
+		if change != nil {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) > 0 {
+		if _, err := c.history().InsertMany(ctx, changes); err != nil {
+			return result.MatchedCount, err
+		}
+	}
+	return result.MatchedCount, nil
+}
+
+// FindArticleHistory returns the recorded changes of an article, oldest first.
+func (c *Mongo) FindArticleHistory(ctx context.Context, id primitive.ObjectID) ([]*ArticleChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := c.history().Find(ctx, bson.M{"articleId": id}, options.Find().SetSort(bson.D{{Key: "changedAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*ArticleChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// recordArticleChange compares an update with the article stored under id and, if it changes any field,
+// adds the change to the history bucket. It must run before the update is applied.
+func recordArticleChange(ctx context.Context, tx documentTx, id primitive.ObjectID, update bson.M, changedAt time.Time) error {
+	change, err := newArticleChange(ctx, tx.get(articlesBucket, id[:]), update, changedAt)
+	if err != nil || change == nil {
+		return err
+	}
+	change.Id = primitive.NewObjectID()
+	return putDocument(tx, historyBucket, change.Id, change)
+}
+
+func (s *documentStore) FindArticleHistory(ctx context.Context, id primitive.ObjectID) ([]*ArticleChange, error) {
+	var changes []*ArticleChange
+	err := s.backend.view(func(tx documentTx) error {
+		var err error
+		changes, err = findDocuments[ArticleChange](tx, historyBucket, bson.M{"articleId": id})
+		return err
+	})
+	return changes, err
+}
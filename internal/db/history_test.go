@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestArticleHistory(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := WithActor(t.Context(), Actor{Command: "sleuth article set", Operator: "reviewer"})
+
+		article := &Article{Url: "https://www.cnn.com/2025/02/26/world/video/a", Location: "Unknown"}
+		require.NoError(t, store.CreateArticle(ctx, article))
+
+		require.NoError(t, store.UpdateArticle(ctx, article.Id, bson.M{"location": "Lake Tahoe", "caseId": int32(7), "overrides.location": FieldOverride{SetBy: "reviewer"}}))
+		// setting the values again changes nothing
+		require.NoError(t, store.UpdateArticle(ctx, article.Id, bson.M{"location": "Lake Tahoe"}))
+		_, err := store.UpdateArticles(t.Context(), bson.M{"caseId": int32(7)}, bson.M{"caseId": int32(8)})
+		require.NoError(t, err)
+
+		history, err := store.FindArticleHistory(ctx, article.Id)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+
+		require.Equal(t, article.Id, history[0].ArticleId)
+		require.Equal(t, "sleuth article set", history[0].Command)
+		require.Equal(t, "reviewer", history[0].Operator)
+		fields := []string{}
+		for _, change := range history[0].Changes {
+			fields = append(fields, change.Field)
+		}
+		require.Equal(t, []string{"caseId", "location", "overrides.location"}, fields)
+		require.Equal(t, "0", FormatValue(history[0].Changes[0].Old))
+		require.Equal(t, `"Unknown"`, FormatValue(history[0].Changes[1].Old))
+		require.Equal(t, `"Lake Tahoe"`, FormatValue(history[0].Changes[1].New))
+		require.Equal(t, "null", FormatValue(history[0].Changes[2].Old))
+
+		// changes made without an actor are still recorded
+		require.Equal(t, "unknown", history[1].Operator)
+		require.Len(t, history[1].Changes, 1)
+		require.Equal(t, "7", FormatValue(history[1].Changes[0].Old))
+		require.Equal(t, "8", FormatValue(history[1].Changes[0].New))
+
+		// the history of a deleted article is kept and ends with the deletion
+		require.NoError(t, store.DeleteArticle(ctx, article.Id))
+		history, err = store.FindArticleHistory(ctx, article.Id)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		require.True(t, history[2].Deleted)
+		require.Equal(t, "reviewer", history[2].Operator)
+		require.Equal(t, `"https://www.cnn.com/2025/02/26/world/video/a"`, FormatValue(history[2].Changes[0].Old))
+	})
+}
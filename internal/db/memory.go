@@ -0,0 +1,116 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Memory stores the collections in memory only. It is empty when created and everything
+// is lost when the process exits, which makes it suitable for tests and dry runs.
+type Memory struct {
+	documentStore
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	backend := &memoryBackend{buckets: make(map[string]map[string][]byte)}
+	for _, name := range documentBuckets {
+		backend.buckets[name] = make(map[string][]byte)
+	}
+	return &Memory{documentStore: documentStore{backend: backend}}
+}
+
+var errReadOnlyTx = errors.New("cannot write in a read-only transaction")
+
+type memoryBackend struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+func (b *memoryBackend) view(fn func(tx documentTx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return fn(&memoryTx{backend: b})
+}
+
+// update runs fn with exclusive access and undoes its changes if it fails.
+func (b *memoryBackend) update(fn func(tx documentTx) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tx := &memoryTx{backend: b, writable: true}
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	return nil
+}
+
+// memoryChange is the value a key had before a write, so that the write can be undone.
+type memoryChange struct {
+	bucket  string
+	key     string
+	value   []byte
+	existed bool
+}
+
+type memoryTx struct {
+	backend  *memoryBackend
+	writable bool
+	undo     []memoryChange
+}
+
+func (t *memoryTx) get(bucket string, key []byte) []byte {
+	return t.backend.buckets[bucket][string(key)]
+}
+
+func (t *memoryTx) record(bucket, key string) {
+	value, existed := t.backend.buckets[bucket][key]
+	t.undo = append(t.undo, memoryChange{bucket: bucket, key: key, value: value, existed: existed})
+}
+
+func (t *memoryTx) put(bucket string, key, value []byte) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	t.record(bucket, string(key))
+	t.backend.buckets[bucket][string(key)] = bytes.Clone(value)
+	return nil
+}
+
+func (t *memoryTx) delete(bucket string, key []byte) error {
+	if !t.writable {
+		return errReadOnlyTx
+	}
+	t.record(bucket, string(key))
+	delete(t.backend.buckets[bucket], string(key))
+	return nil
+}
+
+func (t *memoryTx) forEach(bucket string, fn func(key, value []byte) error) error {
+	docs := t.backend.buckets[bucket]
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn([]byte(key), docs[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *memoryTx) rollback() {
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		change := t.undo[i]
+		if change.existed {
+			t.backend.buckets[change.bucket][change.key] = change.value
+		} else {
+			delete(t.backend.buckets[change.bucket], change.key)
+		}
+	}
+	t.undo = nil
+}
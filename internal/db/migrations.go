@@ -0,0 +1,323 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration brings the stored articles from the previous schema version to Version.
+// Up must be idempotent: it only changes articles whose schemaVersion is below Version,
+// and running it again on an article it already changed leaves the article as it is.
+type Migration struct {
+	Version     int
+	Name        string
+	Description string
+	Up          func(ctx context.Context, store Store) (changed int, err error)
+}
+
+// MigrationRecord records that a migration was applied to the store.
+type MigrationRecord struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"` // MongoDB document ID
+	Version   int                `bson:"version" json:"version"`
+	Name      string             `bson:"name" json:"name"`
+	AppliedAt time.Time          `bson:"appliedAt" json:"appliedAt"`
+	Changed   int                `bson:"changed" json:"changed"` // Articles the migration changed
+}
+
+// Migrations are every migration in the order they are applied. Add new migrations to the end
+// with the next version, and never change one that has been released.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Name:        "relevant-timestamps-fields",
+		Description: "Rename text_snippet and time_detail of relevant timestamps to the fields Article models",
+		Up:          migrateRelevantTimestampsFields,
+	},
+	{
+		Version:     2,
+		Name:        "pipeline-stages",
+		Description: "Record the pipeline stages articles went through, inferred from the fields each stage sets",
+		Up:          migratePipelineStages,
+	},
+}
+
+// CurrentSchemaVersion is the version of the schema new articles are stored with.
+func CurrentSchemaVersion() int {
+	return Migrations[len(Migrations)-1].Version
+}
+
+// belowSchemaVersion returns filters that together match every article whose schema version is
+// below version. Articles stored before schema versions were introduced have no schemaVersion.
+func belowSchemaVersion(version int) []bson.M {
+	return []bson.M{
+		{"schemaVersion": bson.M{"$exists": false}},
+		{"schemaVersion": bson.M{"$lt": version}},
+	}
+}
+
+// PendingMigrations returns the migrations that have not been applied to the store yet, in order.
+func PendingMigrations(ctx context.Context, store Store) ([]Migration, error) {
+	records, err := store.FindMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find applied migrations: %w", err)
+	}
+	applied := make(map[int]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+
+	var pending []Migration
+	for _, m := range Migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// CountArticlesBelowSchemaVersion counts the articles whose schema version is below version.
+func CountArticlesBelowSchemaVersion(ctx context.Context, store Store, version int) (int64, error) {
+	var count int64
+	for _, filter := range belowSchemaVersion(version) {
+		n, err := store.CountArticles(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// Migrate applies every pending migration in order. After a migration has run, every article below
+// its version is moved to it and the migration is recorded, so that it is not applied again.
+// It stops at the first migration that fails and returns the records of the ones that were applied.
+func Migrate(ctx context.Context, store Store) ([]*MigrationRecord, error) {
+	pending, err := PendingMigrations(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []*MigrationRecord
+	for _, m := range pending {
+		log.Info().Int("version", m.Version).Str("name", m.Name).Msg("applying migration")
+		changed, err := m.Up(ctx, store)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d %s failed: %w", m.Version, m.Name, err)
+		}
+		for _, filter := range belowSchemaVersion(m.Version) {
+			if _, err := store.UpdateArticles(ctx, filter, bson.M{"schemaVersion": m.Version}); err != nil {
+				return applied, fmt.Errorf("failed to set schema version %d: %w", m.Version, err)
+			}
+		}
+
+		record := &MigrationRecord{Version: m.Version, Name: m.Name, AppliedAt: time.Now(), Changed: changed}
+		if err := store.RecordMigration(ctx, record); err != nil {
+			return applied, fmt.Errorf("failed to record migration %d %s: %w", m.Version, m.Name, err)
+		}
+		log.Info().Int("version", m.Version).Str("name", m.Name).Int("changed", changed).Msg("applied migration")
+		applied = append(applied, record)
+	}
+	return applied, nil
+}
+
+// FindMigrations returns the migrations applied to the database, oldest version first
+func (c *Mongo) FindMigrations(ctx context.Context) ([]*MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := c.migrations().Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"version": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []*MigrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// RecordMigration inserts the record of an applied migration into the migrations collection
+func (c *Mongo) RecordMigration(ctx context.Context, record *MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := c.migrations().InsertOne(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	record.Id = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// migrateRelevantTimestampsFields rewrites the relevant timestamps of every article below version 1
+// that does not follow RelevantTimestamp. ingest-timestamp-metadata used to store the extracted JSON
+// as it was, with the keys text_snippet and time_detail that Article could not read.
+func migrateRelevantTimestampsFields(ctx context.Context, store Store) (int, error) {
+	changed := 0
+	for _, filter := range belowSchemaVersion(1) {
+		filter["relevantTimestamps"] = bson.M{"$exists": true}
+		projection := bson.M{"relevantTimestamps": 1}
+		for doc, err := range store.ScanArticleDocuments(ctx, filter, ScanOptions{Projection: projection}) {
+			if err != nil {
+				return changed, err
+			}
+			timestamps, rewritten := normalizeRelevantTimestamps(doc["relevantTimestamps"])
+			if !rewritten {
+				continue
+			}
+			id, _ := doc["_id"].(primitive.ObjectID)
+			if err := store.UpdateArticle(ctx, id, bson.M{"relevantTimestamps": timestamps}); err != nil {
+				return changed, fmt.Errorf("failed to update article %s: %w", id.Hex(), err)
+			}
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// relevantTimestampFields maps the keys the timestamp extraction writes to the fields of RelevantTimestamp.
+var relevantTimestampFields = map[string]string{
+	"start":        "start",
+	"end":          "end",
+	"text_snippet": "textSnippet",
+	"textSnippet":  "textSnippet",
+	"location":     "location",
+	"time_detail":  "timeDetail",
+	"timeDetail":   "timeDetail",
+}
+
+// normalizeRelevantTimestamps converts stored relevant timestamps into the fields of RelevantTimestamp.
+// A single entry is wrapped in an array, entries that are not documents are dropped, values that are
+// not strings are formatted as strings and unknown keys are dropped. It reports whether anything changed.
+func normalizeRelevantTimestamps(value any) ([]RelevantTimestamp, bool) {
+	if value == nil {
+		return nil, false
+	}
+	var entries []any
+	rewritten := false
+	switch v := value.(type) {
+	case bson.A:
+		entries = v
+	case []any:
+		entries = v
+	default:
+		entries = []any{v}
+		rewritten = true
+	}
+
+	timestamps := make([]RelevantTimestamp, 0, len(entries))
+	for _, entry := range entries {
+		var fields bson.M
+		switch e := entry.(type) {
+		case bson.M:
+			fields = e
+		case primitive.D:
+			fields = documentToMap(e)
+		default:
+			rewritten = true
+			continue
+		}
+
+		normalized := map[string]string{}
+		for key, value := range fields {
+			field, known := relevantTimestampFields[key]
+			if !known || key != field {
+				rewritten = true
+			}
+			if !known || value == nil {
+				continue
+			}
+			s, isString := value.(string)
+			if !isString {
+				s = fmt.Sprint(value)
+				rewritten = true
+			}
+			normalized[field] = s
+		}
+		timestamps = append(timestamps, RelevantTimestamp{
+			Start:       normalized["start"],
+			End:         normalized["end"],
+			TextSnippet: normalized["textSnippet"],
+			Location:    normalized["location"],
+			TimeDetail:  normalized["timeDetail"],
+		})
+	}
+	return timestamps, rewritten
+}
+
+// migratePipelineStages records the stages of every article below version 2 that has no stages yet.
+// Downloads cannot be inferred from the fields, download-videos records them when it finds the video on disk.
+func migratePipelineStages(ctx context.Context, store Store) (int, error) {
+	now := time.Now()
+	projection := bson.M{
+		"aiHasCheckedIfShouldDownloadVideo": 1,
+		"videoUrl":                          1,
+		"relevantTimestamps":                1,
+		"victimNames":                       1,
+		"location":                          1,
+		"clusterId":                         1,
+	}
+	changed := 0
+	for _, filter := range belowSchemaVersion(2) {
+		filter["stages"] = bson.M{"$exists": false}
+		for doc, err := range store.ScanArticleDocuments(ctx, filter, ScanOptions{Projection: projection}) {
+			if err != nil {
+				return changed, err
+			}
+			stages := inferStages(doc, now)
+			if len(stages) == 0 {
+				continue
+			}
+			id, _ := doc["_id"].(primitive.ObjectID)
+			if err := store.UpdateArticle(ctx, id, bson.M{"stages": stages}); err != nil {
+				return changed, fmt.Errorf("failed to update article %s: %w", id.Hex(), err)
+			}
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// inferStages infers the stages an article went through from the fields each stage sets.
+func inferStages(doc bson.M, now time.Time) map[Stage]StageStatus {
+	has := func(field string) bool {
+		value, found := doc[field]
+		return found && value != nil && value != ""
+	}
+
+	states := map[Stage]StageState{}
+	if checked, _ := doc["aiHasCheckedIfShouldDownloadVideo"].(bool); checked {
+		states[StageScreened] = StageDone
+	}
+	if has("videoUrl") {
+		states[StageResolved] = StageDone
+	}
+	if has("relevantTimestamps") {
+		states[StageTranscribed] = StageDone
+		states[StageTimestamped] = StageDone
+	}
+	switch {
+	case has("victimNames") && has("location"):
+		states[StageEnriched] = StageDone
+	case has("victimNames") || has("location"):
+		states[StageEnriched] = StageStarted
+	}
+	if has("clusterId") {
+		states[StageClustered] = StageDone
+	}
+
+	stages := make(map[Stage]StageStatus, len(states))
+	for stage, state := range states {
+		stages[stage] = StageStatus{}.Next(state, nil, now)
+	}
+	return stages
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNormalizeRelevantTimestamps(t *testing.T) {
+	timestamps, rewritten := normalizeRelevantTimestamps(bson.A{
+		bson.M{"start": "00:12", "end": "00:30", "text_snippet": "police arrived", "time_detail": "night", "extra": 1},
+		"not a document",
+	})
+	require.True(t, rewritten)
+	require.Equal(t, []RelevantTimestamp{{Start: "00:12", End: "00:30", TextSnippet: "police arrived", TimeDetail: "night"}}, timestamps)
+
+	timestamps, rewritten = normalizeRelevantTimestamps(bson.M{"start": "00:01", "textSnippet": "a"})
+	require.True(t, rewritten)
+	require.Equal(t, []RelevantTimestamp{{Start: "00:01", TextSnippet: "a"}}, timestamps)
+
+	_, rewritten = normalizeRelevantTimestamps(bson.A{bson.M{"start": "00:01", "textSnippet": "a", "timeDetail": "b"}})
+	require.False(t, rewritten)
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemory()
+
+	article := &Article{Url: "https://www.cnn.com/2025/02/26/world/video/a", Provider: "cnn"}
+	require.NoError(t, store.CreateArticle(ctx, article))
+	require.Equal(t, CurrentSchemaVersion(), article.SchemaVersion)
+
+	// an article stored before schema versions, as ingest-timestamp-metadata wrote it
+	require.NoError(t, store.UpdateArticle(ctx, article.Id, bson.M{
+		"schemaVersion":      0,
+		"relevantTimestamps": bson.A{bson.M{"start": "00:12", "text_snippet": "police arrived"}},
+	}))
+	outdated, err := CountArticlesBelowSchemaVersion(ctx, store, CurrentSchemaVersion())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), outdated)
+
+	applied, err := Migrate(ctx, store)
+	require.NoError(t, err)
+	require.Len(t, applied, len(Migrations))
+	require.Equal(t, 1, applied[0].Changed)
+
+	found, err := store.FindArticleByUrl(ctx, article.Url)
+	require.NoError(t, err)
+	require.Equal(t, CurrentSchemaVersion(), found.SchemaVersion)
+	require.Equal(t, []RelevantTimestamp{{Start: "00:12", TextSnippet: "police arrived"}}, found.RelevantTimestamps)
+
+	// migrations are applied once
+	applied, err = Migrate(ctx, store)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+	pending, err := PendingMigrations(ctx, store)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // RelevantTimestamp represents one extracted segment where
@@ -20,22 +21,43 @@ type RelevantTimestamp struct {
 	TimeDetail  string `bson:"timeDetail"  json:"time_detail"`  // e.g. "yesterday evening"
 }
 
+// SearchProvenance records one occasion on which a search returned an article.
+type SearchProvenance struct {
+	Query    string             `bson:"query" json:"query"`                         // The search terms that were used
+	QueryId  primitive.ObjectID `bson:"queryId,omitempty" json:"queryId,omitempty"` // The stored query, if the search terms came from one
+	Provider string             `bson:"provider" json:"provider"`
+	Page     int                `bson:"page" json:"page"`       // Results page the article appeared on, starting at 1
+	Rank     int                `bson:"rank" json:"rank"`       // Position of the article on that page, starting at 1
+	FoundAt  time.Time          `bson:"foundAt" json:"foundAt"` // When the search returned the article
+}
+
 // Article represents a news article model.
 type Article struct {
-	Id                                primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"` // MongoDB document ID
-	Title                             string              `bson:"title" json:"title"`
-	Url                               string              `bson:"url" json:"url"`
-	Date                              string              `bson:"date" json:"date"`
-	Description                       string              `bson:"description" json:"description"`
-	Provider                          string              `bson:"provider" json:"provider"`
-	AiHasCheckedIfShouldDownloadVideo bool                `bson:"aiHasCheckedIfShouldDownloadVideo" json:"AiHasCheckedIfShouldDownloadVideo"`
-	AiSuggestsDownloadingVideo        bool                `bson:"aiSuggestsDownloadingVideo" json:"AiSuggestsDownloadingVideo"`
-	VideoPath                         string              `bson:"videoPath" json:"videoPath"` // Path to the downloaded video file
-	VideoUrl                          string              `bson:"videoUrl" json:"videoUrl"`   // Direct URL to the video file
-	RelevantTimestamps                []RelevantTimestamp `bson:"relevantTimestamps" json:"relevantTimestamps"`
-	VictimNames                       []string            `bson:"victimNames" json:"victimNames"`
-	Location                          string              `bson:"location" json:"location"`
-	CaseId                            int32               `bson:"caseId" json:"caseId"` // For case grouping
+	Id                                primitive.ObjectID         `bson:"_id,omitempty" json:"id,omitempty"` // MongoDB document ID
+	Title                             string                     `bson:"title" json:"title"`
+	Url                               string                     `bson:"url" json:"url"`
+	Date                              string                     `bson:"date" json:"date"`
+	Description                       string                     `bson:"description" json:"description"`
+	Provider                          string                     `bson:"provider" json:"provider"`
+	AiHasCheckedIfShouldDownloadVideo bool                       `bson:"aiHasCheckedIfShouldDownloadVideo" json:"AiHasCheckedIfShouldDownloadVideo"`
+	AiSuggestsDownloadingVideo        bool                       `bson:"aiSuggestsDownloadingVideo" json:"AiSuggestsDownloadingVideo"`
+	VideoPath                         string                     `bson:"videoPath" json:"videoPath"` // Path to the downloaded video file
+	VideoUrl                          string                     `bson:"videoUrl" json:"videoUrl"`   // Direct URL to the video file
+	RelevantTimestamps                []RelevantTimestamp        `bson:"relevantTimestamps" json:"relevantTimestamps"`
+	VictimNames                       []string                   `bson:"victimNames" json:"victimNames"`
+	Location                          string                     `bson:"location" json:"location"`
+	CaseId                            int32                      `bson:"caseId" json:"caseId"`                                     // For case grouping
+	DiscoveredVia                     primitive.ObjectID         `bson:"discoveredVia,omitempty" json:"discoveredVia,omitempty"`   // Article whose related videos linked to this one
+	DiscoveryDepth                    int                        `bson:"discoveryDepth,omitempty" json:"discoveryDepth,omitempty"` // Number of related-video hops from a searched article
+	RelatedCrawled                    bool                       `bson:"relatedCrawled" json:"relatedCrawled"`                     // Whether related videos have been extracted from this article
+	Provenance                        []SearchProvenance         `bson:"provenance,omitempty" json:"provenance,omitempty"`         // Every search that returned this article, oldest first
+	ClusterId                         *int                       `bson:"clusterId,omitempty" json:"clusterId,omitempty"`           // Cluster assigned by the clustering pipeline, -1 for noise
+	ClusterConf                       float64                    `bson:"clusterConf,omitempty" json:"clusterConf,omitempty"`       // Confidence of the cluster assignment
+	Embed                             []float64                  `bson:"embed,omitempty" json:"-"`                                 // Embedding the clustering pipeline clustered on
+	SchemaVersion                     int                        `bson:"schemaVersion" json:"schemaVersion"`                       // Version of the schema the document follows, see Migrations
+	Stages                            map[Stage]StageStatus      `bson:"stages,omitempty" json:"stages,omitempty"`                 // Progress through every stage of the pipeline the article started
+	AiProvenance                      map[string]FieldProvenance `bson:"aiProvenance,omitempty" json:"aiProvenance,omitempty"`     // How a model derived each AI-derived field, by the field's name
+	Overrides                         map[string]FieldOverride   `bson:"overrides,omitempty" json:"overrides,omitempty"`           // Fields set by hand, by the field's name
 }
 
 // CreateArticle inserts a new article into the provided MongoDB collection.
@@ -45,6 +67,10 @@ func (c *Mongo) CreateArticle(ctx context.Context, article *Article) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	if article.SchemaVersion == 0 {
+		article.SchemaVersion = CurrentSchemaVersion()
+	}
+
 	// Insert the article document into the collection.
 	result, err := c.articles().InsertOne(ctx, article)
 	if err != nil {
@@ -70,7 +96,7 @@ func (c *Mongo) FindArticleByUrl(ctx context.Context, url string) (*Article, err
 	err := c.articles().FindOne(ctx, filter).Decode(&article)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("article not found")
+			return nil, ErrArticleNotFound
 		}
 		return nil, err
 	}
@@ -78,6 +104,40 @@ func (c *Mongo) FindArticleByUrl(ctx context.Context, url string) (*Article, err
 	return &article, nil
 }
 
+// FindArticleByID searches for an article by its MongoDB ObjectID
+func (c *Mongo) FindArticleByID(ctx context.Context, id primitive.ObjectID) (*Article, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var article Article
+	err := c.articles().FindOne(ctx, bson.M{"_id": id}).Decode(&article)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrArticleNotFound
+		}
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// AddArticleProvenance appends a search provenance entry to the article with the given url.
+// It returns an error if no article has that url.
+func (c *Mongo) AddArticleProvenance(ctx context.Context, url string, entry SearchProvenance) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"url": url}
+	result, err := c.articles().UpdateOne(ctx, filter, bson.M{"$push": bson.M{"provenance": entry}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no article found to update")
+	}
+	return nil
+}
+
 func (c *Mongo) FindAllArticles(ctx context.Context) ([]*Article, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -140,26 +200,6 @@ func (c *Mongo) FindAllArticlesNotChecked(ctx context.Context) ([]*Article, erro
 	return articles, nil
 }
 
-// UpdateArticle updates an article in the collection by its MongoDB ObjectID.
-// It returns an error if no article is found or if the operation fails.
-func (c *Mongo) UpdateArticle(ctx context.Context, id primitive.ObjectID, update bson.M) error {
-	// Set a timeout for the operation.
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	filter := bson.M{"_id": id}
-
-	// Update the document matching the given _id.
-	result, err := c.articles().UpdateOne(ctx, filter, bson.M{"$set": update})
-	if err != nil {
-		return err
-	}
-	if result.MatchedCount == 0 {
-		return errors.New("no article found to update")
-	}
-	return nil
-}
-
 // FindArticlesByFilter searches for articles based on a filter.
 // It returns a slice of articles that match the filter criteria.
 func (c *Mongo) FindArticlesByFilter(ctx context.Context, filter bson.M) ([]*Article, error) {
@@ -190,13 +230,28 @@ func (c *Mongo) FindArticlesByFilter(ctx context.Context, filter bson.M) ([]*Art
 	return articles, nil
 }
 
+// QueryRun records the outcome of running a query against the enabled providers.
+type QueryRun struct {
+	RanAt             time.Time `bson:"ranAt" json:"ranAt"`
+	Providers         []string  `bson:"providers" json:"providers"`
+	NewArticles       int       `bson:"newArticles" json:"newArticles"`             // Articles that were not in the database before
+	DuplicateArticles int       `bson:"duplicateArticles" json:"duplicateArticles"` // Results that were already in the database
+}
+
 // Query represents a search query generated by AI
 type Query struct {
-	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"` // MongoDB document ID
-	Query       string             `bson:"query" json:"query"`                // The search query
-	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`        // When the query was generated
-	Used        bool               `bson:"used" json:"used"`                  // Whether this query has been used in a search
-	Description string             `bson:"description" json:"description"`    // Optional description or context for the query
+	Id             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`                        // MongoDB document ID
+	Query          string             `bson:"query" json:"query"`                                       // The search query
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`                               // When the query was generated
+	Used           bool               `bson:"used" json:"used"`                                         // Whether this query has been used in a search
+	Description    string             `bson:"description" json:"description"`                           // Optional description or context for the query
+	LastRunAt      time.Time          `bson:"lastRunAt,omitempty" json:"lastRunAt,omitempty"`           // When the query was last run
+	Runs           []QueryRun         `bson:"runs,omitempty" json:"runs,omitempty"`                     // Every run of the query, oldest first
+	Embedding      []float64          `bson:"embedding,omitempty" json:"-"`                             // Used to reject new queries that are too similar
+	EmbeddingModel string             `bson:"embeddingModel,omitempty" json:"embeddingModel,omitempty"` // The model that produced Embedding
+	TemplateId     primitive.ObjectID `bson:"templateId,omitempty" json:"templateId,omitempty"`         // The template this query was expanded from
+	TemplateValues map[string]string  `bson:"templateValues,omitempty" json:"templateValues,omitempty"` // The value substituted for each template variable
+	AdHoc          bool               `bson:"adHoc,omitempty" json:"adHoc,omitempty"`                   // Given to the search command rather than generated or expanded, left out of yields and generation
 }
 
 // CreateQuery inserts a new query into the queries collection
@@ -321,22 +376,108 @@ func (c *Mongo) UpdateQuery(ctx context.Context, id primitive.ObjectID, update b
 	return nil
 }
 
-// // DeleteArticle removes an article from the collection by its MongoDB ObjectID.
-// // It returns an error if no article is found or if the operation fails.
-// func (c *Mongo) DeleteArticle(ctx context.Context, id primitive.ObjectID) error {
-// 	// Set a timeout for the operation.
-// 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-// 	defer cancel()
-
-// 	filter := bson.M{"_id": id}
-
-// 	// Delete the document matching the given _id.
-// 	result, err := c.ArticlesCollection().DeleteOne(ctx, filter)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	if result.DeletedCount == 0 {
-// 		return errors.New("no article found to delete")
-// 	}
-// 	return nil
-// }
+// RecordQueryRun marks a query as used and appends the outcome of a run to it.
+func (c *Mongo) RecordQueryRun(ctx context.Context, id primitive.ObjectID, run QueryRun) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id}
+	update := bson.M{
+		"$set":  bson.M{"used": true, "lastRunAt": run.RanAt},
+		"$push": bson.M{"runs": run},
+	}
+	result, err := c.queries().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no query found to update")
+	}
+	return nil
+}
+
+// QueryTemplate represents a query with variables, such as "{setting} body found {state}",
+// that is expanded into one query per combination of variable values
+type QueryTemplate struct {
+	Id        primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"` // MongoDB document ID
+	Template  string              `bson:"template" json:"template"`          // The template text
+	Variables map[string][]string `bson:"variables" json:"variables"`        // Every value each variable has been expanded with
+	CreatedAt time.Time           `bson:"createdAt" json:"createdAt"`        // When the template was first expanded
+}
+
+// CreateQueryTemplate inserts a new query template into the queryTemplates collection
+func (c *Mongo) CreateQueryTemplate(ctx context.Context, template *QueryTemplate) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if template.CreatedAt.IsZero() {
+		template.CreatedAt = time.Now()
+	}
+
+	result, err := c.queryTemplates().InsertOne(ctx, template)
+	if err != nil {
+		return err
+	}
+
+	template.Id = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindQueryTemplateByValue searches for a query template by its template text
+func (c *Mongo) FindQueryTemplateByValue(ctx context.Context, templateString string) (*QueryTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var template QueryTemplate
+	filter := bson.M{"template": templateString}
+
+	err := c.queryTemplates().FindOne(ctx, filter).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("query template not found")
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// UpdateQueryTemplate updates a query template in the collection by its MongoDB ObjectID
+func (c *Mongo) UpdateQueryTemplate(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id}
+
+	result, err := c.queryTemplates().UpdateOne(ctx, filter, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no query template found to update")
+	}
+	return nil
+}
+
+// DeleteArticle removes an article from the collection by its MongoDB ObjectID and records
+// the deletion in the history collection.
+// It returns an error if no article is found or if the operation fails.
+func (c *Mongo) DeleteArticle(ctx context.Context, id primitive.ObjectID) error {
+	// Set a timeout for the operation.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id}
+
+	// Delete the document matching the given _id, returning its url for the history.
+	opts := options.FindOneAndDelete().SetProjection(bson.M{"url": 1})
+	stored, err := c.articles().FindOneAndDelete(ctx, filter, opts).Raw()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("no article found to delete")
+		}
+		return err
+	}
+	_, err = c.history().InsertOne(ctx, newArticleDeletion(ctx, stored, time.Now()))
+	return err
+}
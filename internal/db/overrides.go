@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FieldOverride records that a person set the value of a field by hand.
+type FieldOverride struct {
+	SetBy    string    `bson:"setBy" json:"setBy"` // Who set the value
+	SetAt    time.Time `bson:"setAt" json:"setAt"`
+	Locked   bool      `bson:"locked" json:"locked"`                         // Locked values are never overwritten by the commands that derive the field
+	LockedAt time.Time `bson:"lockedAt,omitempty" json:"lockedAt,omitempty"` // When the value was locked
+}
+
+// OverridePath is the path of the override of a field within an article, to set it
+// in the same update as the field.
+func OverridePath(field string) string {
+	return "overrides." + field
+}
+
+// Locked reports whether the field of the article was set by hand and locked.
+func (a *Article) Locked(field string) bool {
+	return a.Overrides[field].Locked
+}
+
+// ExcludeLocked adds a condition to filter that leaves out the articles whose field is locked,
+// and returns the filter.
+func ExcludeLocked(filter bson.M, field string) bson.M {
+	filter[OverridePath(field)+".locked"] = bson.M{"$ne": true}
+	return filter
+}
+
+// EditableFields are the fields of an article that can be set by hand, with the parser of their values.
+var EditableFields = map[string]func(value string) (any, error){
+	"title":       parseString,
+	"date":        parseString,
+	"description": parseString,
+	"location":    parseString,
+	"victimNames": parseList,
+	"caseId": func(value string) (any, error) {
+		n, err := strconv.ParseInt(value, 10, 32)
+		return int32(n), err
+	},
+	"aiSuggestsDownloadingVideo": func(value string) (any, error) {
+		return strconv.ParseBool(value)
+	},
+}
+
+// ParseFieldValue parses a value given by hand for one of the EditableFields. Lists are separated by semicolons.
+func ParseFieldValue(field, value string) (any, error) {
+	parse, found := EditableFields[field]
+	if !found {
+		editable := make([]string, 0, len(EditableFields))
+		for name := range EditableFields {
+			editable = append(editable, name)
+		}
+		sort.Strings(editable)
+		return nil, fmt.Errorf("%s cannot be set by hand, the editable fields are %s", field, strings.Join(editable, ", "))
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", field, err)
+	}
+	return parsed, nil
+}
+
+func parseString(value string) (any, error) {
+	return strings.TrimSpace(value), nil
+}
+
+func parseList(value string) (any, error) {
+	items := []string{}
+	for _, item := range strings.Split(value, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
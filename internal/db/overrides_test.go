@@ -0,0 +1,50 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseFieldValue(t *testing.T) {
+	value, err := ParseFieldValue("victimNames", " Jane Doe; John Doe ;")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Jane Doe", "John Doe"}, value)
+
+	value, err = ParseFieldValue("caseId", "12")
+	require.NoError(t, err)
+	require.Equal(t, int32(12), value)
+
+	_, err = ParseFieldValue("aiSuggestsDownloadingVideo", "maybe")
+	require.Error(t, err)
+	_, err = ParseFieldValue("url", "https://www.cnn.com")
+	require.ErrorContains(t, err, "cannot be set by hand")
+}
+
+func TestExcludeLocked(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := t.Context()
+
+		locked := &Article{Url: "https://www.cnn.com/video/locked"}
+		require.NoError(t, store.CreateArticle(ctx, locked))
+		require.NoError(t, store.UpdateArticle(ctx, locked.Id, bson.M{
+			"location":               "Lake Tahoe",
+			OverridePath("location"): FieldOverride{SetBy: "analyst", SetAt: time.Now(), Locked: true, LockedAt: time.Now()},
+		}))
+		require.NoError(t, store.CreateArticle(ctx, &Article{Url: "https://www.cnn.com/video/unlocked"}))
+
+		found, err := store.FindArticleByID(ctx, locked.Id)
+		require.NoError(t, err)
+		require.True(t, found.Locked("location"))
+		require.False(t, found.Locked("victimNames"))
+
+		var urls []string
+		for article, err := range store.ScanArticles(ctx, ExcludeLocked(bson.M{}, "location"), ScanOptions{}) {
+			require.NoError(t, err)
+			urls = append(urls, article.Url)
+		}
+		require.Equal(t, []string{"https://www.cnn.com/video/unlocked"}, urls)
+	})
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Prompt is a system prompt a command derives a field with. Bump the version when
+// the meaning of the prompt changes, so that the values it derived can be told apart.
+type Prompt struct {
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+// Hash returns the SHA-256 of the prompt's text, which tells edits apart that did not bump the version.
+func (p Prompt) Hash() string {
+	sum := sha256.Sum256([]byte(p.Text))
+	return hex.EncodeToString(sum[:])
+}
+
+// FieldProvenance records how a model derived the value of a field.
+type FieldProvenance struct {
+	Model         string    `bson:"model" json:"model"`
+	PromptVersion int       `bson:"promptVersion" json:"promptVersion"`
+	PromptHash    string    `bson:"promptHash" json:"promptHash"`
+	DerivedAt     time.Time `bson:"derivedAt" json:"derivedAt"`
+	RawResponse   string    `bson:"rawResponse" json:"rawResponse"` // Output of the model before it was parsed
+}
+
+// NewFieldProvenance records that model derived a value with prompt and answered response.
+func NewFieldProvenance(model string, prompt Prompt, response string) FieldProvenance {
+	return FieldProvenance{
+		Model:         model,
+		PromptVersion: prompt.Version,
+		PromptHash:    prompt.Hash(),
+		DerivedAt:     time.Now(),
+		RawResponse:   response,
+	}
+}
+
+// ProvenancePath is the path of the provenance of a field within an article, to set it
+// in the same update as the field.
+func ProvenancePath(field string) string {
+	return "aiProvenance." + field
+}
+
+// DerivedBy reports whether the field of the article was derived by model with prompt.
+// Values without provenance were derived before it was recorded and are never derived by them.
+func (a *Article) DerivedBy(field, model string, prompt Prompt) bool {
+	provenance, found := a.AiProvenance[field]
+	return found &&
+		provenance.Model == model &&
+		provenance.PromptVersion == prompt.Version &&
+		provenance.PromptHash == prompt.Hash()
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleDerivedBy(t *testing.T) {
+	prompt := Prompt{Version: 1, Text: "Determine the location."}
+	article := &Article{AiProvenance: map[string]FieldProvenance{
+		"location": NewFieldProvenance("llama3.1", prompt, "Denver, Colorado"),
+	}}
+
+	require.True(t, article.DerivedBy("location", "llama3.1", prompt))
+	require.False(t, article.DerivedBy("location", "llama3.2", prompt))
+	require.False(t, article.DerivedBy("location", "llama3.1", Prompt{Version: 2, Text: prompt.Text}))
+	// an edit that did not bump the version
+	require.False(t, article.DerivedBy("location", "llama3.1", Prompt{Version: 1, Text: "Determine the city."}))
+	// values without provenance
+	require.False(t, article.DerivedBy("victimNames", "llama3.1", prompt))
+	require.False(t, (&Article{}).DerivedBy("location", "llama3.1", prompt))
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RunSearch records the search of one provider for one query during a run.
+type RunSearch struct {
+	Query       string               `bson:"query" json:"query"`
+	QueryId     primitive.ObjectID   `bson:"queryId,omitempty" json:"queryId,omitempty"`
+	Provider    string               `bson:"provider" json:"provider"`
+	Pages       int                  `bson:"pages" json:"pages"`             // Result pages visited
+	NewArticles int                  `bson:"newArticles" json:"newArticles"` // Articles that were not in the database before
+	Duplicates  int                  `bson:"duplicates" json:"duplicates"`   // Results that were already in the database
+	ArticleIds  []primitive.ObjectID `bson:"articleIds,omitempty" json:"articleIds,omitempty"`
+	Error       string               `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// RunCheckpoint records how far the search of one provider for one query got during a run.
+type RunCheckpoint struct {
+	Query     string             `bson:"query" json:"query"`
+	QueryId   primitive.ObjectID `bson:"queryId" json:"queryId"`
+	Provider  string             `bson:"provider" json:"provider"`
+	Page      int                `bson:"page" json:"page"` // Last page whose results were all saved; for Fox each "Load More" click is a page
+	Done      bool               `bson:"done" json:"done"` // Whether the search went through every page
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// CheckpointKey returns the key of the checkpoint of a provider's search for a query.
+func CheckpointKey(provider string, queryId primitive.ObjectID) string {
+	return provider + ":" + queryId.Hex()
+}
+
+// SearchRun represents one run of the search command, or one scheduled run of the watch command.
+type SearchRun struct {
+	Id          primitive.ObjectID       `bson:"_id,omitempty" json:"id,omitempty"`                  // MongoDB document ID
+	Queries     []string                 `bson:"queries" json:"queries"`                             // Search terms of the run
+	Providers   []string                 `bson:"providers" json:"providers"`                         // Providers that were searched
+	StartedAt   time.Time                `bson:"startedAt" json:"startedAt"`                         // When the run started
+	FinishedAt  time.Time                `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`   // When the run finished, zero while it is running
+	Version     string                   `bson:"version" json:"version"`                             // Version of the sleuth CLI
+	Searches    []RunSearch              `bson:"searches" json:"searches"`                           // Every search of the run, in order
+	NewArticles int                      `bson:"newArticles" json:"newArticles"`                     // Total over all searches
+	Duplicates  int                      `bson:"duplicates" json:"duplicates"`                       // Total over all searches
+	Errors      []string                 `bson:"errors,omitempty" json:"errors,omitempty"`           // Errors of failed searches and of the run itself
+	Checkpoints map[string]RunCheckpoint `bson:"checkpoints,omitempty" json:"checkpoints,omitempty"` // Pagination position of every search, by CheckpointKey
+	ResumedAt   []time.Time              `bson:"resumedAt,omitempty" json:"resumedAt,omitempty"`     // When the run was resumed
+}
+
+// CreateRun inserts a new run into the runs collection
+func (c *Mongo) CreateRun(ctx context.Context, run *SearchRun) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if run.StartedAt.IsZero() {
+		run.StartedAt = time.Now()
+	}
+
+	result, err := c.runs().InsertOne(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	run.Id = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// AddRunSearches appends searches to a run as they finish, so that a run that is interrupted still
+// records what it did
+func (c *Mongo) AddRunSearches(ctx context.Context, id primitive.ObjectID, searches []RunSearch) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	newArticles, duplicates := 0, 0
+	var errs []string
+	for _, s := range searches {
+		newArticles += s.NewArticles
+		duplicates += s.Duplicates
+		if s.Error != "" {
+			errs = append(errs, s.Provider+": "+s.Error)
+		}
+	}
+	update := bson.M{
+		"$push": bson.M{"searches": bson.M{"$each": searches}},
+		"$inc":  bson.M{"newArticles": newArticles, "duplicates": duplicates},
+	}
+	if len(errs) > 0 {
+		update["$push"].(bson.M)["errors"] = bson.M{"$each": errs}
+	}
+	result, err := c.runs().UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no run found to update")
+	}
+	return nil
+}
+
+// SaveRunCheckpoint stores the pagination position of a provider's search for a query
+func (c *Mongo) SaveRunCheckpoint(ctx context.Context, id primitive.ObjectID, checkpoint RunCheckpoint) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if checkpoint.UpdatedAt.IsZero() {
+		checkpoint.UpdatedAt = time.Now()
+	}
+	key := "checkpoints." + CheckpointKey(checkpoint.Provider, checkpoint.QueryId)
+	result, err := c.runs().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{key: checkpoint}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no run found to update")
+	}
+	return nil
+}
+
+// ResumeRun marks a run as running again
+func (c *Mongo) ResumeRun(ctx context.Context, id primitive.ObjectID, resumedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$unset": bson.M{"finishedAt": ""},
+		"$push":  bson.M{"resumedAt": resumedAt},
+	}
+	result, err := c.runs().UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no run found to update")
+	}
+	return nil
+}
+
+// FinishRun records the end of a run, with any errors of the run itself
+func (c *Mongo) FinishRun(ctx context.Context, id primitive.ObjectID, finishedAt time.Time, runErrors []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"finishedAt": finishedAt}}
+	if len(runErrors) > 0 {
+		update["$push"] = bson.M{"errors": bson.M{"$each": runErrors}}
+	}
+	result, err := c.runs().UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no run found to update")
+	}
+	return nil
+}
+
+// FindRunByID searches for a run by its MongoDB ObjectID
+func (c *Mongo) FindRunByID(ctx context.Context, id primitive.ObjectID) (*SearchRun, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var run SearchRun
+	err := c.runs().FindOne(ctx, bson.M{"_id": id}).Decode(&run)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("run not found")
+		}
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// FindRecentRuns returns the most recently started runs, newest first. A limit of 0 returns every run.
+func (c *Mongo) FindRecentRuns(ctx context.Context, limit int64) ([]*SearchRun, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var runs []*SearchRun
+	findOptions := options.Find().SetSort(bson.M{"startedAt": -1}).SetLimit(limit)
+	cursor, err := c.runs().Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var run SearchRun
+		if err := cursor.Decode(&run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, &run)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,329 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultScanBatchSize is the number of documents a scan reads at a time if ScanOptions does not set one.
+const DefaultScanBatchSize = 500
+
+// scanBatchTimeout bounds reading a single batch, so that a scan can run for as long as its caller needs.
+const scanBatchTimeout = 30 * time.Second
+
+// WithoutEmbeddings is a projection that leaves out the embeddings the clustering pipeline adds,
+// which are by far the largest part of an article and are not needed by the commands.
+var WithoutEmbeddings = bson.M{"embed": 0}
+
+// ScanOptions control how a scan reads the documents.
+type ScanOptions struct {
+	// BatchSize is the number of documents read from the store at a time, DefaultScanBatchSize if zero.
+	// Keep it small if the caller takes long for every document: MongoDB closes cursors that are idle
+	// for ten minutes.
+	BatchSize int32
+	// Projection selects the fields that are read, following MongoDB's syntax: either the fields
+	// to include (1) or the fields to exclude (0). The _id is included unless it is excluded.
+	Projection bson.M
+	// NewestFirst reads the documents in the reverse order of their IDs, which start with their creation time.
+	NewestFirst bool
+	// Limit is the number of documents read at most, all of them if zero.
+	Limit int64
+}
+
+func (o ScanOptions) batchSize() int32 {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return DefaultScanBatchSize
+}
+
+// ScanArticles streams the articles that match the filter, reading them in batches. The scan stops at
+// the first error, which is yielded with a nil article. Breaking out of the loop closes the scan.
+func (c *Mongo) ScanArticles(ctx context.Context, filter bson.M, opts ScanOptions) iter.Seq2[*Article, error] {
+	return scanCollection[Article](ctx, c.articles(), filter, opts)
+}
+
+// ScanArticleDocuments streams the stored documents of the articles that match the filter,
+// including fields that Article does not model.
+func (c *Mongo) ScanArticleDocuments(ctx context.Context, filter bson.M, opts ScanOptions) iter.Seq2[bson.M, error] {
+	return dereference(scanCollection[bson.M](ctx, c.articles(), filter, opts))
+}
+
+// CountArticles counts the articles that match the filter
+func (c *Mongo) CountArticles(ctx context.Context, filter bson.M) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, scanBatchTimeout)
+	defer cancel()
+
+	return c.articles().CountDocuments(ctx, filter)
+}
+
+// scanCollection streams the documents of a collection. Every batch gets its own timeout
+// instead of the whole scan, so that the scan is only limited by ctx.
+func scanCollection[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, opts ScanOptions) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		findOptions := options.Find().SetBatchSize(opts.batchSize())
+		if len(opts.Projection) > 0 {
+			findOptions.SetProjection(opts.Projection)
+		}
+		if opts.NewestFirst {
+			findOptions.SetSort(bson.D{{Key: "_id", Value: -1}})
+		}
+		if opts.Limit > 0 {
+			findOptions.SetLimit(opts.Limit)
+		}
+
+		findCtx, cancel := context.WithTimeout(ctx, scanBatchTimeout)
+		cursor, err := collection.Find(findCtx, filter, findOptions)
+		cancel()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer cursor.Close(context.Background())
+
+		for {
+			nextCtx, cancel := context.WithTimeout(ctx, scanBatchTimeout)
+			next := cursor.Next(nextCtx)
+			cancel()
+			if !next {
+				break
+			}
+			var doc T
+			if err := cursor.Decode(&doc); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(&doc, nil) {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func (s *documentStore) ScanArticles(ctx context.Context, filter bson.M, opts ScanOptions) iter.Seq2[*Article, error] {
+	return scanDocuments[Article](ctx, s.backend, articlesBucket, filter, opts)
+}
+
+func (s *documentStore) ScanArticleDocuments(ctx context.Context, filter bson.M, opts ScanOptions) iter.Seq2[bson.M, error] {
+	return dereference(scanDocuments[bson.M](ctx, s.backend, articlesBucket, filter, opts))
+}
+
+func (s *documentStore) CountArticles(ctx context.Context, filter bson.M) (int64, error) {
+	var count int64
+	err := s.backend.view(func(tx documentTx) error {
+		ids, err := matchingKeys(tx, articlesBucket, filter)
+		count = int64(len(ids))
+		return err
+	})
+	return count, err
+}
+
+// scanDocuments streams the documents of a bucket that match the filter, in insertion order, which is
+// the order of their IDs.
+// The matching keys are collected first, then every batch is read in its own transaction and
+// yielded after the transaction ended, so that the caller can write to the store while it scans.
+// Documents deleted since the scan started are skipped.
+func scanDocuments[T any](ctx context.Context, backend documentBackend, bucket string, filter bson.M, opts ScanOptions) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		var keys [][]byte
+		err := backend.view(func(tx documentTx) error {
+			var err error
+			keys, err = matchingKeys(tx, bucket, filter)
+			return err
+		})
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if opts.NewestFirst {
+			slices.Reverse(keys)
+		}
+		if opts.Limit > 0 && int64(len(keys)) > opts.Limit {
+			keys = keys[:opts.Limit]
+		}
+
+		batchSize := int(opts.batchSize())
+		for start := 0; start < len(keys); start += batchSize {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			batch := keys[start:min(start+batchSize, len(keys))]
+			docs := make([]*T, 0, len(batch))
+			err := backend.view(func(tx documentTx) error {
+				for _, key := range batch {
+					data := tx.get(bucket, key)
+					if data == nil {
+						continue
+					}
+					var doc T
+					if err := decodeProjected(data, opts.Projection, &doc); err != nil {
+						return err
+					}
+					docs = append(docs, &doc)
+				}
+				return nil
+			})
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, doc := range docs {
+				if !yield(doc, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// matchingKeys returns the keys of the documents of a bucket that match the filter.
+func matchingKeys(tx documentTx, bucket string, filter bson.M) ([][]byte, error) {
+	var keys [][]byte
+	err := tx.forEach(bucket, func(key, data []byte) error {
+		if len(filter) > 0 {
+			var m bson.M
+			if err := bson.Unmarshal(data, &m); err != nil {
+				return err
+			}
+			matched, err := matchFilter(m, filter)
+			if err != nil || !matched {
+				return err
+			}
+		}
+		// keys are only valid for the life of the transaction
+		keys = append(keys, bytes.Clone(key))
+		return nil
+	})
+	return keys, err
+}
+
+// decodeProjected decodes a stored document into doc, keeping only the fields the projection selects.
+func decodeProjected(data []byte, projection bson.M, doc any) error {
+	if len(projection) == 0 {
+		return bson.Unmarshal(data, doc)
+	}
+	var m bson.M
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	projected, err := projectDocument(m, projection)
+	if err != nil {
+		return err
+	}
+	data, err = bson.Marshal(projected)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, doc)
+}
+
+// projectDocument applies a projection to a document like MongoDB does. Fields are given as dotted
+// paths, and a projection either includes or excludes fields, apart from the _id.
+func projectDocument(doc bson.M, projection bson.M) (bson.M, error) {
+	var included, excluded []string
+	for path, value := range projection {
+		include, err := projectionIncludes(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid projection of %s: %w", path, err)
+		}
+		switch {
+		case path == "_id":
+		case include:
+			included = append(included, path)
+		default:
+			excluded = append(excluded, path)
+		}
+	}
+	if len(included) > 0 && len(excluded) > 0 {
+		return nil, errors.New("a projection cannot both include and exclude fields")
+	}
+
+	includeId := true
+	if value, found := projection["_id"]; found {
+		includeId, _ = projectionIncludes(value)
+	}
+
+	if len(included) > 0 {
+		projected := bson.M{}
+		if id, found := doc["_id"]; found && includeId {
+			projected["_id"] = id
+		}
+		for _, path := range included {
+			if value, found := lookupPath(doc, path); found {
+				if err := setPath(projected, path, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return projected, nil
+	}
+
+	for _, path := range excluded {
+		deletePath(doc, path)
+	}
+	if !includeId {
+		delete(doc, "_id")
+	}
+	return doc, nil
+}
+
+func projectionIncludes(value any) (bool, error) {
+	switch v := normalizeValue(value).(type) {
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	default:
+		return false, fmt.Errorf("expected 0 or 1, got %v", value)
+	}
+}
+
+// deletePath removes the field at a dotted path from doc, if it is there.
+func deletePath(doc bson.M, path string) {
+	keys := strings.Split(path, ".")
+	current := doc
+	for _, key := range keys[:len(keys)-1] {
+		switch n := current[key].(type) {
+		case bson.M:
+			current = n
+		case primitive.D:
+			child := documentToMap(n)
+			current[key] = child
+			current = child
+		default:
+			return
+		}
+	}
+	delete(current, keys[len(keys)-1])
+}
+
+// dereference turns a scan of document pointers into a scan of documents.
+func dereference[T any](seq iter.Seq2[*T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for doc, err := range seq {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !yield(*doc, nil) {
+				return
+			}
+		}
+	}
+}
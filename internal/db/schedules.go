@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ScheduleResult records the outcome of the last run of a schedule.
+type ScheduleResult struct {
+	StartedAt   time.Time `bson:"startedAt" json:"startedAt"`
+	FinishedAt  time.Time `bson:"finishedAt" json:"finishedAt"`
+	NewArticles int       `bson:"newArticles" json:"newArticles"`
+	Checked     int       `bson:"checked" json:"checked"`       // New articles checked by AI check, if chained
+	Downloaded  int       `bson:"downloaded" json:"downloaded"` // Videos downloaded, if chained
+	Error       string    `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// Schedule represents a saved set of queries that the watch command runs on a cron schedule.
+type Schedule struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`                // MongoDB document ID
+	Name          string             `bson:"name" json:"name"`                                 // Unique name of the schedule
+	Cron          string             `bson:"cron" json:"cron"`                                 // Five-field cron expression
+	Queries       []string           `bson:"queries" json:"queries"`                           // Search terms to run
+	FromQueries   bool               `bson:"fromQueries" json:"fromQueries"`                   // Also run every unused query from the queries collection
+	Providers     []string           `bson:"providers" json:"providers"`                       // Providers to search
+	ChainAicheck  bool               `bson:"chainAicheck" json:"chainAicheck"`                 // Run AI check on new articles
+	ChainDownload bool               `bson:"chainDownload" json:"chainDownload"`               // Download videos of new articles approved by AI check
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`                       // When the schedule was added
+	NextRunAt     time.Time          `bson:"nextRunAt" json:"nextRunAt"`                       // When the schedule is due next
+	LastRunAt     time.Time          `bson:"lastRunAt,omitempty" json:"lastRunAt,omitempty"`   // When the schedule was last claimed for a run
+	LastResult    *ScheduleResult    `bson:"lastResult,omitempty" json:"lastResult,omitempty"` // Outcome of the last finished run
+}
+
+// CreateSchedule inserts a new schedule into the schedules collection
+func (c *Mongo) CreateSchedule(ctx context.Context, schedule *Schedule) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if schedule.CreatedAt.IsZero() {
+		schedule.CreatedAt = time.Now()
+	}
+
+	result, err := c.schedules().InsertOne(ctx, schedule)
+	if err != nil {
+		return err
+	}
+
+	schedule.Id = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindScheduleByName searches for a schedule by its name
+func (c *Mongo) FindScheduleByName(ctx context.Context, name string) (*Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var schedule Schedule
+	err := c.schedules().FindOne(ctx, bson.M{"name": name}).Decode(&schedule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("schedule not found")
+		}
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// FindAllSchedules returns all schedules in the collection
+func (c *Mongo) FindAllSchedules(ctx context.Context) ([]*Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var schedules []*Schedule
+	cursor, err := c.schedules().Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var schedule Schedule
+		if err := cursor.Decode(&schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, &schedule)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// DeleteScheduleByName removes a schedule from the collection by its name
+func (c *Mongo) DeleteScheduleByName(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := c.schedules().DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("no schedule found to delete")
+	}
+	return nil
+}
+
+// ClaimScheduleRun moves a due schedule on to its next run. The update only applies while the
+// schedule is still due at dueAt, so when several watchers see the same due schedule, or a
+// restarted watcher sees a run that was already claimed, exactly one of them gets claimed == true.
+func (c *Mongo) ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, dueAt, nextRunAt time.Time) (claimed bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "nextRunAt": dueAt}
+	update := bson.M{"$set": bson.M{"nextRunAt": nextRunAt, "lastRunAt": time.Now()}}
+	result, err := c.schedules().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RecordScheduleResult stores the outcome of the last run of a schedule
+func (c *Mongo) RecordScheduleResult(ctx context.Context, id primitive.ObjectID, result ScheduleResult) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	updateResult, err := c.schedules().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastResult": result}})
+	if err != nil {
+		return err
+	}
+	if updateResult.MatchedCount == 0 {
+		return errors.New("no schedule found to update")
+	}
+	return nil
+}
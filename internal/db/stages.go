@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Stage is a step of the pipeline every article goes through.
+type Stage string
+
+const (
+	StageScreened    Stage = "screened"    // aicheck decided whether to download the video
+	StageResolved    Stage = "resolved"    // The direct URL of the video was determined
+	StageDownloaded  Stage = "downloaded"  // The video was downloaded
+	StageTranscribed Stage = "transcribed" // The audio of the video was transcribed
+	StageTimestamped Stage = "timestamped" // The relevant timestamps of the transcript were stored
+	StageEnriched    Stage = "enriched"    // The victim names and the location were determined
+	StageClustered   Stage = "clustered"   // The clustering pipeline assigned a cluster
+)
+
+// Stages are the stages of the pipeline in the order an article goes through them.
+var Stages = []Stage{StageScreened, StageResolved, StageDownloaded, StageTranscribed, StageTimestamped, StageEnriched, StageClustered}
+
+// StageState is where an article is in a stage. An article that has no status for a stage has not started it.
+type StageState string
+
+const (
+	StageStarted StageState = "started" // Work on the stage began and has not finished, or only part of it is done
+	StageDone    StageState = "done"
+	StageFailed  StageState = "failed"
+)
+
+// StageStatus records the progress of an article through a stage.
+type StageStatus struct {
+	State       StageState `bson:"state" json:"state"`
+	Attempts    int        `bson:"attempts" json:"attempts"`                           // How often the stage was attempted
+	StartedAt   time.Time  `bson:"startedAt" json:"startedAt"`                         // When the stage was first attempted
+	UpdatedAt   time.Time  `bson:"updatedAt" json:"updatedAt"`                         // When the state last changed
+	CompletedAt time.Time  `bson:"completedAt,omitempty" json:"completedAt,omitempty"` // When the stage was last done
+	LastError   string     `bson:"lastError,omitempty" json:"lastError,omitempty"`     // Why the last failed attempt failed
+}
+
+// Next returns the status after the stage moved to state at the given time. Starting a stage counts as
+// an attempt, and so does finishing it without starting it first. stageErr is recorded if the stage failed.
+func (s StageStatus) Next(state StageState, stageErr error, now time.Time) StageStatus {
+	if state == StageStarted || s.State != StageStarted {
+		s.Attempts++
+	}
+	if s.StartedAt.IsZero() {
+		s.StartedAt = now
+	}
+	s.State = state
+	s.UpdatedAt = now
+	switch state {
+	case StageDone:
+		s.CompletedAt = now
+		s.LastError = ""
+	case StageFailed:
+		s.LastError = "unknown error"
+		if stageErr != nil {
+			s.LastError = stageErr.Error()
+		}
+	}
+	return s
+}
+
+// Stuck reports whether an article needs attention in the stage: it failed, or it was started and
+// has not changed for longer than after.
+func (s StageStatus) Stuck(now time.Time, after time.Duration) bool {
+	switch s.State {
+	case StageFailed:
+		return true
+	case StageStarted:
+		return now.Sub(s.UpdatedAt) > after
+	default:
+		return false
+	}
+}
+
+// stagePath is the path of the status of a stage within an article.
+func stagePath(stage Stage) string {
+	return "stages." + string(stage)
+}
+
+// RecordArticleStage moves an article to state in the stage.
+func (c *Mongo) RecordArticleStage(ctx context.Context, id primitive.ObjectID, stage Stage, state StageState, stageErr error) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var current struct {
+		Stages map[Stage]StageStatus `bson:"stages"`
+	}
+	projection := options.FindOne().SetProjection(bson.M{stagePath(stage): 1})
+	if err := c.articles().FindOne(ctx, bson.M{"_id": id}, projection).Decode(&current); err != nil {
+		return err
+	}
+
+	status := current.Stages[stage].Next(state, stageErr, time.Now())
+	_, err := c.articles().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{stagePath(stage): status}})
+	return err
+}
+
+func (s *documentStore) RecordArticleStage(ctx context.Context, id primitive.ObjectID, stage Stage, state StageState, stageErr error) error {
+	return s.backend.update(func(tx documentTx) error {
+		found, err := modifyDocument(tx, articlesBucket, id, func(doc *bson.M) error {
+			var current StageStatus
+			if value, found := lookupPath(*doc, stagePath(stage)); found {
+				data, err := bson.Marshal(value)
+				if err != nil {
+					return err
+				}
+				if err := bson.Unmarshal(data, &current); err != nil {
+					return err
+				}
+			}
+			return setPath(*doc, stagePath(stage), current.Next(state, stageErr, time.Now()))
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("no article found to update")
+		}
+		return nil
+	})
+}
+
+// StartStage records in Models that work on the stage of an article began.
+// Failing to record it is logged, it does not stop the pipeline.
+func StartStage(ctx context.Context, id primitive.ObjectID, stage Stage) {
+	recordStage(ctx, id, stage, StageStarted, nil)
+}
+
+// FinishStage records in Models that the stage of an article is done, or failed if stageErr is not nil.
+// Failing to record it is logged, it does not stop the pipeline.
+func FinishStage(ctx context.Context, id primitive.ObjectID, stage Stage, stageErr error) {
+	state := StageDone
+	if stageErr != nil {
+		state = StageFailed
+	}
+	recordStage(ctx, id, stage, state, stageErr)
+}
+
+func recordStage(ctx context.Context, id primitive.ObjectID, stage Stage, state StageState, stageErr error) {
+	if err := Models.RecordArticleStage(ctx, id, stage, state, stageErr); err != nil {
+		log.Err(err).Str("id", id.Hex()).Str("stage", string(stage)).Str("state", string(state)).Msg("failed to record article stage")
+	}
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestStageStatusNext(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	status := StageStatus{}.Next(StageStarted, nil, start)
+	require.Equal(t, StageStarted, status.State)
+	require.Equal(t, 1, status.Attempts)
+
+	// finishing a started stage is the same attempt
+	status = status.Next(StageFailed, errors.New("timeout"), start.Add(time.Minute))
+	require.Equal(t, 1, status.Attempts)
+	require.Equal(t, "timeout", status.LastError)
+	require.True(t, status.Stuck(start.Add(time.Minute), time.Hour))
+
+	status = status.Next(StageDone, nil, start.Add(2*time.Minute))
+	require.Equal(t, 2, status.Attempts)
+	require.Equal(t, start, status.StartedAt)
+	require.Equal(t, start.Add(2*time.Minute), status.CompletedAt)
+	require.Empty(t, status.LastError)
+	require.False(t, status.Stuck(start.Add(48*time.Hour), time.Hour))
+
+	started := StageStatus{}.Next(StageStarted, nil, start)
+	require.False(t, started.Stuck(start.Add(time.Minute), time.Hour))
+	require.True(t, started.Stuck(start.Add(2*time.Hour), time.Hour))
+}
+
+func TestStoreRecordArticleStage(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := t.Context()
+
+		article := &Article{Url: "https://www.cnn.com/2025/02/26/world/video/a", Provider: "cnn"}
+		require.NoError(t, store.CreateArticle(ctx, article))
+		require.NoError(t, store.RecordArticleStage(ctx, article.Id, StageDownloaded, StageStarted, nil))
+		require.NoError(t, store.RecordArticleStage(ctx, article.Id, StageDownloaded, StageFailed, errors.New("403")))
+		require.NoError(t, store.RecordArticleStage(ctx, article.Id, StageScreened, StageDone, nil))
+
+		found, err := store.FindArticleByUrl(ctx, article.Url)
+		require.NoError(t, err)
+		require.Equal(t, StageFailed, found.Stages[StageDownloaded].State)
+		require.Equal(t, 1, found.Stages[StageDownloaded].Attempts)
+		require.Equal(t, "403", found.Stages[StageDownloaded].LastError)
+		require.Equal(t, StageDone, found.Stages[StageScreened].State)
+
+		count, err := store.CountArticles(ctx, bson.M{"stages.downloaded.state": string(StageFailed)})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+	})
+}
+
+func TestInferStages(t *testing.T) {
+	now := time.Now()
+	stages := inferStages(bson.M{
+		"aiHasCheckedIfShouldDownloadVideo": true,
+		"videoUrl":                          "https://cdn.cnn.com/video.mp4",
+		"relevantTimestamps":                bson.A{},
+		"victimNames":                       bson.A{"Jane Doe"},
+		"location":                          "",
+	}, now)
+	require.Equal(t, StageDone, stages[StageScreened].State)
+	require.Equal(t, StageDone, stages[StageResolved].State)
+	require.Equal(t, StageDone, stages[StageTranscribed].State)
+	require.Equal(t, StageStarted, stages[StageEnriched].State)
+	require.NotContains(t, stages, StageDownloaded)
+	require.NotContains(t, stages, StageClustered)
+
+	require.Empty(t, inferStages(bson.M{"aiHasCheckedIfShouldDownloadVideo": false, "location": ""}, now))
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Store is the storage of every collection. Filters and updates use MongoDB's syntax;
+// backends other than MongoDB support equality and the $exists, $ne, $eq, $in, $nin, $lt, $lte,
+// $gt and $gte operators, and updates that set fields. The Find methods load every match at once;
+// commands that go through the whole collection use the Scan methods, which stream it in batches.
+type Store interface {
+	// Articles
+	CreateArticle(ctx context.Context, article *Article) error
+	FindArticleByUrl(ctx context.Context, url string) (*Article, error)
+	FindArticleByID(ctx context.Context, id primitive.ObjectID) (*Article, error)
+	AddArticleProvenance(ctx context.Context, url string, entry SearchProvenance) error
+	FindAllArticles(ctx context.Context) ([]*Article, error)
+	FindAllArticlesNotChecked(ctx context.Context) ([]*Article, error)
+	UpdateArticle(ctx context.Context, id primitive.ObjectID, update bson.M) error
+	FindArticlesByFilter(ctx context.Context, filter bson.M) ([]*Article, error)
+	ScanArticles(ctx context.Context, filter bson.M, opts ScanOptions) iter.Seq2[*Article, error]
+	ScanArticleDocuments(ctx context.Context, filter bson.M, opts ScanOptions) iter.Seq2[bson.M, error]
+	CountArticles(ctx context.Context, filter bson.M) (int64, error)
+	RecordArticleStage(ctx context.Context, id primitive.ObjectID, stage Stage, state StageState, stageErr error) error
+	UpdateArticles(ctx context.Context, filter bson.M, update bson.M) (int64, error)
+	DeleteArticle(ctx context.Context, id primitive.ObjectID) error
+	FindArticleHistory(ctx context.Context, id primitive.ObjectID) ([]*ArticleChange, error)
+
+	// Queries and query templates
+	CreateQuery(ctx context.Context, query *Query) error
+	FindQueryByValue(ctx context.Context, queryString string) (*Query, error)
+	FindAllQueries(ctx context.Context) ([]*Query, error)
+	FindUnusedQueries(ctx context.Context) ([]*Query, error)
+	UpdateQuery(ctx context.Context, id primitive.ObjectID, update bson.M) error
+	RecordQueryRun(ctx context.Context, id primitive.ObjectID, run QueryRun) error
+	CreateQueryTemplate(ctx context.Context, template *QueryTemplate) error
+	FindQueryTemplateByValue(ctx context.Context, templateString string) (*QueryTemplate, error)
+	UpdateQueryTemplate(ctx context.Context, id primitive.ObjectID, update bson.M) error
+
+	// Schedules
+	CreateSchedule(ctx context.Context, schedule *Schedule) error
+	FindScheduleByName(ctx context.Context, name string) (*Schedule, error)
+	FindAllSchedules(ctx context.Context) ([]*Schedule, error)
+	DeleteScheduleByName(ctx context.Context, name string) error
+	ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, dueAt, nextRunAt time.Time) (claimed bool, err error)
+	RecordScheduleResult(ctx context.Context, id primitive.ObjectID, result ScheduleResult) error
+
+	// Runs
+	CreateRun(ctx context.Context, run *SearchRun) error
+	AddRunSearches(ctx context.Context, id primitive.ObjectID, searches []RunSearch) error
+	SaveRunCheckpoint(ctx context.Context, id primitive.ObjectID, checkpoint RunCheckpoint) error
+	ResumeRun(ctx context.Context, id primitive.ObjectID, resumedAt time.Time) error
+	FinishRun(ctx context.Context, id primitive.ObjectID, finishedAt time.Time, runErrors []string) error
+	FindRunByID(ctx context.Context, id primitive.ObjectID) (*SearchRun, error)
+	FindRecentRuns(ctx context.Context, limit int64) ([]*SearchRun, error)
+
+	// Migrations
+	FindMigrations(ctx context.Context) ([]*MigrationRecord, error)
+	RecordMigration(ctx context.Context, record *MigrationRecord) error
+
+	// Custody log
+	AppendCustodyEntry(ctx context.Context, entry *CustodyEntry) error
+	FindCustodyEntries(ctx context.Context) ([]*CustodyEntry, error)
+
+	// Backups
+	ScanDocuments(ctx context.Context, collection string, opts ScanOptions) iter.Seq2[bson.Raw, error]
+	InsertDocuments(ctx context.Context, collection string, docs []bson.Raw) (int64, error)
+	DeleteAllDocuments(ctx context.Context, collection string) error
+}
+
+// Models is the store every command works with, set by Connect.
+var Models Store
+
+// ErrDuplicateUrl is returned when an article with the same url is already stored.
+var ErrDuplicateUrl = errors.New("an article with this url already exists")
+
+// ErrArticleNotFound is returned when no article has the ID or url that was looked up.
+var ErrArticleNotFound = errors.New("article not found")
+
+// IsDuplicateKeyError reports whether an article could not be created because its url is already stored.
+func IsDuplicateKeyError(err error) bool {
+	return errors.Is(err, ErrDuplicateUrl) || mongo.IsDuplicateKeyError(err)
+}
+
+// GetDatabaseURI returns the URI of the store to use. DATABASE_URI takes precedence over MONGODB_URI.
+//
+// The scheme selects the backend:
+//   - mongodb:// or mongodb+srv:// connects to MongoDB
+//   - bolt:// opens the single-file embedded database at the path that follows, e.g. bolt://sleuth.db,
+//     or the file of the profile's database next to it
+//   - memory:// starts with an empty store in memory that is discarded when the process exits
+func GetDatabaseURI() string {
+	if uri := os.Getenv("DATABASE_URI"); uri != "" {
+		return uri
+	}
+	return GetMongoURI()
+}
+
+// Connect opens the store selected by the URI's scheme and makes it the store in Models.
+// Long running commands connect once, later calls reuse the store.
+func Connect(uri string) error {
+	if Models != nil {
+		return nil
+	}
+
+	scheme, rest, found := strings.Cut(uri, "://")
+	if !found {
+		return fmt.Errorf("database URI %q has no scheme", uri)
+	}
+	switch scheme {
+	case "mongodb", "mongodb+srv":
+		store := &Mongo{}
+		if err := store.ConnectDatabase(uri); err != nil {
+			return err
+		}
+		Models = store
+	case "bolt":
+		store, err := OpenBolt(boltPath(rest))
+		if err != nil {
+			return err
+		}
+		Models = store
+	case "memory":
+		log.Warn().Msg("using an in-memory database, nothing will be saved")
+		Models = NewMemory()
+	default:
+		return fmt.Errorf("unsupported database URI scheme %q", scheme)
+	}
+	return nil
+}
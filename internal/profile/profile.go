@@ -0,0 +1,211 @@
+// Package profile defines investigation profiles. A profile bundles the database, the prompts, the query
+// seeds and the providers of a dataset, so that the same binary can build datasets on different topics.
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers/cnn"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers/fox"
+)
+
+// Prompts are what the commands that use a model ask it. Bump the version of a prompt when its
+// meaning changes, so that --recompute-outdated derives the fields again.
+type Prompts struct {
+	Screening   db.Prompt `json:"screening"`   // aicheck: whether the video of an article should be downloaded, answered with true or false
+	Victim      db.Prompt `json:"victim"`      // determine-victim: the names of the victims, separated by semicolons, or Unknown
+	Location    db.Prompt `json:"location"`    // determine-location: the location, or Unknown
+	Query       string    `json:"query"`       // generate-queries: asks for a search query
+	QuerySystem string    `json:"querySystem"` // generate-queries: the system prompt that keeps the answer to a single query
+}
+
+// Profile is an investigation: the dataset it builds and how it finds and screens articles.
+type Profile struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Database    string   `json:"database,omitempty"` // MongoDB database the collections are in
+	Prompts     Prompts  `json:"prompts"`
+	Seeds       []string `json:"seeds,omitempty"` // Search queries to start the dataset with, also given to generate-queries as examples
+	Providers   []string `json:"providers"`       // Providers search uses unless --providers is given
+}
+
+// allProviders are the providers search can use.
+var allProviders = []string{cnn.ProviderCNN, fox.ProviderFoxNews}
+
+// DefaultName is the name of the built-in profile, which is used unless another one is selected.
+const DefaultName = "bodies-found"
+
+// Default is the built-in profile, which finds missing persons and bodies found. It is the only profile
+// that uses the sleuth database.
+var Default = Profile{
+	Name:        DefaultName,
+	Description: "Missing persons and bodies found",
+	Database:    "sleuth",
+	Prompts: Prompts{
+		Screening: db.Prompt{
+			Version: 1,
+			Text: `We are building a dataset on crime cases where bodies were found. I will provide you with a video title and description and you will decide if the video should be downloaded for further processing.
+
+A video would be useful if it may contain information about
+
+- A case where a body may eventually be found
+- A missing person report
+- A solved case about a missing person
+
+Respond with "true" or "false" depending on if the video should be downloaded (true) or not (false).
+`,
+		},
+		Victim: db.Prompt{
+			Version: 1,
+			Text: `You are an AI helping to identify victims in news articles about missing persons and bodies found.
+Based on the article information provided, determine the name(s) of the victim(s).
+If multiple victims are mentioned, return all of their names separated by semicolons (;).
+Return ONLY the victim names with no explanations or additional text.
+If you cannot determine any victim's name, respond with "Unknown".
+Do not include titles (Mr., Mrs., Dr., etc.) unless they are part of a formal name like "Dr. Martin Luther King Jr.".
+Format your response as: "Name1; Name2; Name3" if multiple victims are present.
+`,
+		},
+		Location: db.Prompt{
+			Version: 1,
+			Text: `You are an AI helping to identify locations in news articles about missing persons and bodies found.
+Based on the article information provided, determine the location where the body was found or the incident occurred.
+Return ONLY the location name with no explanations or additional text.
+Be as specific as possible, including city, state, country, or other geographical indicators if available.
+If you cannot determine a location, respond with "Unknown".
+Format your response as a simple location string, for example: "Denver, Colorado" or "Lake Michigan near Chicago".
+`,
+		},
+		Query: `Generate a single search query that would be effective for finding news articles about missing persons cases or cases where bodies have been found. 
+The query should be specific enough to return relevant results but general enough to capture a wide range of cases. 
+Focus on creating search terms that would help build a dataset for tracking missing persons and bodies found.
+Return only the search query string with no explanations or additional text.`,
+		QuerySystem: `You are helping to build a dataset of news articles about missing persons and bodies found. 
+Generate only a single search query that would help find relevant news articles.
+Return ONLY the search query with no explanations, quotes, or additional text.
+Do not use any special characters or database syntax.
+Do not join multiple queries together in any way.
+Do not attempt to return more than one query at a time.
+Do not use conjunctions (and, or, +, etc.) to combine multiple queries.
+`,
+	},
+	Seeds:     []string{"body found in lake", "remains discovered woods", "missing person case solved"},
+	Providers: allProviders,
+}
+
+// Active is the profile every command works with, set by the --profile flag before a command runs.
+var Active = Default
+
+// Dir returns the directory profiles are loaded from: SLEUTH_PROFILES_DIR, or ./profiles.
+func Dir() string {
+	if dir := os.Getenv("SLEUTH_PROFILES_DIR"); dir != "" {
+		return dir
+	}
+	return "profiles"
+}
+
+// Load returns a profile by its name: the built-in profile, or the JSON file <name>.json in dir.
+// A name that ends in .json is read as the path of the file instead. The name of a profile is the name of
+// its file, its database defaults to sleuth-<name> and its providers to every provider.
+func Load(dir, name string) (Profile, error) {
+	if name == "" || name == DefaultName {
+		return Default, nil
+	}
+
+	path := filepath.Join(dir, name+".json")
+	if strings.HasSuffix(name, ".json") {
+		path = name
+		name = strings.TrimSuffix(filepath.Base(name), ".json")
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Profile{}, fmt.Errorf("profile %q not found, expected it in %s", name, path)
+	}
+	if err != nil {
+		return Profile{}, err
+	}
+
+	var p Profile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return Profile{}, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = name
+	}
+	// a copy of the built-in profile must not write into its dataset
+	if p.Name != name {
+		return Profile{}, fmt.Errorf("invalid profile %s: it is named %q, name it after its file or leave the name out", path, p.Name)
+	}
+	if p.Database == Default.Database {
+		return Profile{}, fmt.Errorf("invalid profile %s: the database %q is the one of the built-in profile", path, p.Database)
+	}
+	if p.Database == "" {
+		p.Database = "sleuth-" + p.Name
+	}
+	if len(p.Providers) == 0 {
+		p.Providers = allProviders
+	}
+	for _, prompt := range []*db.Prompt{&p.Prompts.Screening, &p.Prompts.Victim, &p.Prompts.Location} {
+		if prompt.Version == 0 {
+			prompt.Version = 1
+		}
+	}
+	if err := p.validate(); err != nil {
+		return Profile{}, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// validate checks that the profile has every prompt, as the built-in prompts are about another topic,
+// and that its providers exist.
+func (p Profile) validate() error {
+	for _, provider := range p.Providers {
+		if !slices.Contains(allProviders, provider) {
+			return fmt.Errorf("unknown provider %q, expected one of %s", provider, strings.Join(allProviders, ", "))
+		}
+	}
+	var missing []string
+	prompts := map[string]string{
+		"screening":   p.Prompts.Screening.Text,
+		"victim":      p.Prompts.Victim.Text,
+		"location":    p.Prompts.Location.Text,
+		"query":       p.Prompts.Query,
+		"querySystem": p.Prompts.QuerySystem,
+	}
+	for name, text := range prompts {
+		if strings.TrimSpace(text) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("missing prompts: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// List returns the names of the built-in profile and of the profiles in dir.
+func List(dir string) ([]string, error) {
+	names := []string{DefaultName}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if name != DefaultName {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,94 @@
+package profile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func writeProfile(t *testing.T, dir, name string, p any) {
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".json"), data, 0o644))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	// the built-in profile needs no file
+	p, err := Load(dir, DefaultName)
+	require.NoError(t, err)
+	require.Equal(t, "sleuth", p.Database)
+
+	wildfires := Profile{
+		Prompts: Prompts{
+			Screening:   db.Prompt{Text: "Is this article about a wildfire fatality?"},
+			Victim:      db.Prompt{Version: 2, Text: "Who died in the wildfire?"},
+			Location:    db.Prompt{Text: "Where was the wildfire?"},
+			Query:       "Generate a search query about wildfire fatalities.",
+			QuerySystem: "Return only the query.",
+		},
+		Seeds:     []string{"wildfire victims identified"},
+		Providers: []string{"cnn"},
+	}
+	writeProfile(t, dir, "wildfires", wildfires)
+
+	// the name, database and prompt versions are defaulted, and a path can be given instead of a name
+	for _, name := range []string{"wildfires", filepath.Join(dir, "wildfires.json")} {
+		p, err = Load(dir, name)
+		require.NoError(t, err)
+		require.Equal(t, "wildfires", p.Name)
+		require.Equal(t, "sleuth-wildfires", p.Database)
+		require.Equal(t, 1, p.Prompts.Screening.Version)
+		require.Equal(t, 2, p.Prompts.Victim.Version)
+		require.Equal(t, []string{"cnn"}, p.Providers)
+	}
+
+	// providers default to every provider
+	wildfires.Providers = nil
+	writeProfile(t, dir, "wildfires", wildfires)
+	p, err = Load(dir, "wildfires")
+	require.NoError(t, err)
+	require.Equal(t, Default.Providers, p.Providers)
+
+	_, err = Load(dir, "missing-children")
+	require.ErrorContains(t, err, "not found")
+
+	// a profile must not fall back to the prompts of another topic
+	incomplete := wildfires
+	incomplete.Prompts.Location.Text = ""
+	incomplete.Prompts.QuerySystem = " "
+	writeProfile(t, dir, "incomplete", incomplete)
+	_, err = Load(dir, "incomplete")
+	require.ErrorContains(t, err, "missing prompts: location, querySystem")
+
+	unknown := wildfires
+	unknown.Providers = []string{"bbc"}
+	writeProfile(t, dir, "unknown", unknown)
+	_, err = Load(dir, "unknown")
+	require.ErrorContains(t, err, `unknown provider "bbc"`)
+
+	// a copy of the built-in profile must be renamed and must not use its database
+	copied := wildfires
+	copied.Name = DefaultName
+	writeProfile(t, dir, "copied", copied)
+	_, err = Load(dir, "copied")
+	require.ErrorContains(t, err, `it is named "bodies-found"`)
+	copied.Name = ""
+	copied.Database = "sleuth"
+	writeProfile(t, dir, "copied", copied)
+	_, err = Load(dir, "copied")
+	require.ErrorContains(t, err, `the database "sleuth" is the one of the built-in profile`)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "typo.json"), []byte(`{"prompt": {}}`), 0o644))
+	_, err = Load(dir, "typo")
+	require.ErrorContains(t, err, "unknown field")
+
+	names, err := List(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{DefaultName, "copied", "incomplete", "typo", "unknown", "wildfires"}, names)
+}
@@ -0,0 +1,104 @@
+package sleuth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrInvalidCrawlDepth = errors.New("crawl depth must be at least 1")
+
+// crawlItem is an article waiting to have its related videos extracted.
+type crawlItem struct {
+	article *db.Article
+	depth   int
+}
+
+// CrawlRelated visits every article the AI check approved and saves the related videos
+// linked from its page as new articles. Newly discovered articles are crawled in turn
+// until maxDepth hops away from the approved article. Articles that were already crawled
+// are skipped unless recrawl is set.
+func CrawlRelated(ctx context.Context, maxDepth int, recrawl bool) error {
+	if maxDepth < 1 {
+		return ErrInvalidCrawlDepth
+	}
+
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	filter := bson.M{"aiSuggestsDownloadingVideo": true}
+	if !recrawl {
+		filter["relatedCrawled"] = bson.M{"$ne": true}
+	}
+	count, err := db.Models.CountArticles(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to count approved articles: %w", err)
+	}
+	log.Info().Int64("count", count).Int("maxDepth", maxDepth).Msg("crawling related videos")
+
+	// Stream the approved articles in small batches, every crawl loads a page in the browser
+	discovered := 0
+	approved := db.Models.ScanArticles(ctx, filter, db.ScanOptions{BatchSize: 10, Projection: db.WithoutEmbeddings})
+	for article, err := range approved {
+		if err != nil {
+			return fmt.Errorf("failed to find approved articles: %w", err)
+		}
+		discovered += crawlFrom(ctx, article, maxDepth)
+	}
+
+	log.Info().Int("discovered", discovered).Msg("finished crawling related videos")
+	return nil
+}
+
+// crawlFrom crawls the related videos of an approved article and of the videos it discovers, breadth first,
+// until maxDepth hops away from it. It returns the number of videos it discovered.
+func crawlFrom(ctx context.Context, approved *db.Article, maxDepth int) int {
+	discovered := 0
+	queue := []crawlItem{{article: approved}}
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+
+		provider, ok := newProvider(ctx, item.article.Provider).(providers.RelatedProvider)
+		if !ok {
+			log.Debug().Str("provider", item.article.Provider).Str("url", item.article.Url).Msg("provider does not support related videos, skipping")
+			continue
+		}
+
+		related, err := provider.Related(item.article.Url)
+		if err != nil {
+			log.Err(err).Str("url", item.article.Url).Msg("failed to extract related videos, skipping for now")
+			continue
+		}
+
+		for i := range related {
+			article := related[i]
+			article.DiscoveredVia = item.article.Id
+			article.DiscoveryDepth = item.depth + 1
+			if err := db.Models.CreateArticle(ctx, &article); err != nil {
+				if !db.IsDuplicateKeyError(err) {
+					log.Err(err).Str("url", article.Url).Msg("failed to save related video")
+				}
+				continue
+			}
+			discovered++
+			log.Debug().Str("title", article.Title).Str("url", article.Url).Str("parent", item.article.Url).Msg("Found related video")
+			if article.DiscoveryDepth < maxDepth {
+				queue = append(queue, crawlItem{article: &article, depth: article.DiscoveryDepth})
+			}
+		}
+
+		if err := db.Models.UpdateArticle(ctx, item.article.Id, bson.M{"relatedCrawled": true}); err != nil {
+			log.Err(err).Str("url", item.article.Url).Msg("failed to mark article as crawled")
+		}
+		log.Info().Str("url", item.article.Url).Int("related", len(related)).Int("depth", item.depth).Msg("crawled related videos")
+	}
+	return discovered
+}
@@ -0,0 +1,50 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2025, 3, 5, 10, 17, 30, 0, time.UTC)
+
+	cases := []struct {
+		expression string
+		want       time.Time
+	}{
+		{"* * * * *", time.Date(2025, 3, 5, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2025, 3, 6, 9, 30, 0, 0, time.UTC)},
+		{"0 8 * * 1-5", time.Date(2025, 3, 6, 8, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"5,45 10 * * *", time.Date(2025, 3, 5, 10, 45, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		t.Run(c.expression, func(t *testing.T) {
+			s, err := Parse(c.expression)
+			require.NoError(t, err)
+			require.Equal(t, c.want, s.Next(from))
+		})
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	require.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(expression)
+		require.Error(t, err, expression)
+	}
+}
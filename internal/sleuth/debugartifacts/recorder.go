@@ -0,0 +1,180 @@
+package debugartifacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/rs/zerolog/log"
+)
+
+// maxRequests is the number of most recent network requests kept for the artifacts.
+const maxRequests = 50
+
+// captureTimeout is how long taking the screenshot and the rendered HTML may take.
+// The scrape's own context has usually expired by the time it failed, so the capture gets its own.
+const captureTimeout = 15 * time.Second
+
+// Request is a network request made by the browser tab.
+type Request struct {
+	id     network.RequestID
+	Url    string    `json:"url"`
+	Method string    `json:"method"`
+	Type   string    `json:"type,omitempty"`
+	Status int64     `json:"status,omitempty"` // HTTP status of the response, zero if none was received
+	Error  string    `json:"error,omitempty"`  // Why loading failed, if it did
+	SentAt time.Time `json:"sentAt"`
+}
+
+// Recorder keeps the console log and the last network requests of a chromedp tab,
+// so that they can be saved together with a screenshot and the rendered HTML when a scrape fails.
+// A nil Recorder records nothing, which is what Listen returns when debug artifacts are disabled.
+type Recorder struct {
+	dir string
+
+	mu       sync.Mutex
+	console  []string
+	requests []*Request
+	byId     map[network.RequestID]*Request
+}
+
+// Listen starts recording the tab of the chromedp context. Artifacts are saved under dir,
+// and nothing is recorded if dir is empty.
+func Listen(ctx context.Context, dir string) *Recorder {
+	if dir == "" {
+		return nil
+	}
+	r := &Recorder{dir: dir, byId: make(map[network.RequestID]*Request)}
+	chromedp.ListenTarget(ctx, r.handleEvent)
+	return r
+}
+
+func (r *Recorder) handleEvent(ev any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		var args []string
+		for _, arg := range ev.Args {
+			args = append(args, consoleValue(arg))
+		}
+		r.console = append(r.console, fmt.Sprintf("%s %s: %s", eventTime(ev.Timestamp), ev.Type, strings.Join(args, " ")))
+	case *runtime.EventExceptionThrown:
+		if ev.ExceptionDetails != nil {
+			r.console = append(r.console, fmt.Sprintf("%s exception: %s", eventTime(ev.Timestamp), ev.ExceptionDetails.Error()))
+		}
+	case *network.EventRequestWillBeSent:
+		if ev.Request == nil {
+			return
+		}
+		request := &Request{id: ev.RequestID, Url: ev.Request.URL, Method: ev.Request.Method, Type: ev.Type.String(), SentAt: time.Now()}
+		r.byId[ev.RequestID] = request
+		r.requests = append(r.requests, request)
+		if len(r.requests) > maxRequests {
+			delete(r.byId, r.requests[0].id)
+			r.requests = r.requests[1:]
+		}
+	case *network.EventResponseReceived:
+		if request, found := r.byId[ev.RequestID]; found && ev.Response != nil {
+			request.Status = ev.Response.Status
+		}
+	case *network.EventLoadingFailed:
+		if request, found := r.byId[ev.RequestID]; found {
+			request.Error = ev.ErrorText
+		}
+	}
+}
+
+func consoleValue(arg *runtime.RemoteObject) string {
+	if len(arg.Value) > 0 {
+		var s string
+		if err := json.Unmarshal(arg.Value, &s); err == nil {
+			return s
+		}
+		return string(arg.Value)
+	}
+	return arg.Description
+}
+
+func eventTime(ts *runtime.Timestamp) string {
+	if ts == nil {
+		return time.Now().Format(time.RFC3339)
+	}
+	return ts.Time().Format(time.RFC3339)
+}
+
+// Save writes a full-page screenshot, the rendered HTML, the console log, the last network
+// requests and the error of a failed scrape to a new directory and logs its path.
+// The chromedp context must be the tab's context without the scrape's timeout.
+// It returns the directory, or an empty string if nothing was saved.
+func (r *Recorder) Save(ctx context.Context, name string, cause error) string {
+	if r == nil {
+		return ""
+	}
+
+	dir := filepath.Join(r.dir, artifactDirName(name, time.Now()))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Err(err).Str("dir", dir).Msg("failed to create debug artifacts directory")
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+
+	var screenshot []byte
+	if err := chromedp.Run(ctx, chromedp.FullScreenshot(&screenshot, 100)); err != nil {
+		log.Err(err).Msg("failed to take debug screenshot")
+	} else {
+		r.write(dir, "screenshot.png", screenshot)
+	}
+
+	var html string
+	if err := chromedp.Run(ctx, chromedp.OuterHTML("html", &html, chromedp.ByQuery)); err != nil {
+		log.Err(err).Msg("failed to get rendered HTML for debugging")
+	} else {
+		r.write(dir, "page.html", []byte(html))
+	}
+
+	r.mu.Lock()
+	console := strings.Join(r.console, "\n")
+	requests, err := json.MarshalIndent(r.requests, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		log.Err(err).Msg("failed to encode network requests for debugging")
+	} else {
+		r.write(dir, "network.json", requests)
+	}
+	r.write(dir, "console.log", []byte(console))
+	r.write(dir, "error.txt", []byte(cause.Error()))
+
+	log.Error().Err(cause).Str("artifacts", dir).Msg("saved debug artifacts of failed scrape")
+	return dir
+}
+
+func (r *Recorder) write(dir, name string, data []byte) {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		log.Err(err).Str("path", path).Msg("failed to write debug artifact")
+	}
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// artifactDirName returns a file system safe directory name for the artifacts of a failed scrape.
+func artifactDirName(name string, at time.Time) string {
+	name = strings.Trim(unsafeNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 60 {
+		name = strings.TrimRight(name[:60], "-")
+	}
+	return fmt.Sprintf("%s-%s", at.UTC().Format("20060102T150405.000Z"), name)
+}
@@ -0,0 +1,31 @@
+package debugartifacts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactDirName(t *testing.T) {
+	at := time.Date(2025, 3, 1, 12, 30, 45, 0, time.UTC)
+	require.Equal(t, "20250301T123045.000Z-cnn-search-body-found-in-lake", artifactDirName("cnn search Body found in lake!", at))
+	require.Equal(t, "20250301T123045.000Z-video-page", artifactDirName("../video page/", at))
+}
+
+func TestRecorderKeepsLastRequests(t *testing.T) {
+	r := &Recorder{byId: make(map[network.RequestID]*Request)}
+	for i := 0; i < maxRequests+5; i++ {
+		id := network.RequestID(time.Duration(i).String())
+		r.handleEvent(&network.EventRequestWillBeSent{RequestID: id, Request: &network.Request{URL: "https://www.cnn.com/" + string(id), Method: "GET"}})
+		r.handleEvent(&network.EventResponseReceived{RequestID: id, Response: &network.Response{Status: 200}})
+	}
+	r.handleEvent(&network.EventLoadingFailed{RequestID: "0s", ErrorText: "net::ERR_ABORTED"})
+
+	require.Len(t, r.requests, maxRequests)
+	require.Len(t, r.byId, maxRequests)
+	require.Equal(t, "https://www.cnn.com/5ns", r.requests[0].Url)
+	require.Equal(t, int64(200), r.requests[0].Status)
+	require.Empty(t, r.requests[0].Error)
+}
@@ -0,0 +1,179 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/rs/zerolog/log"
+)
+
+// interstitialPollInterval is how often WaitReady checks for the page or a known interstitial.
+const interstitialPollInterval = 500 * time.Millisecond
+
+// Interstitial is a consent wall, cookie banner or autoplay overlay that keeps a page
+// from showing its content until it is dismissed.
+type Interstitial struct {
+	Name    string `json:"name"`    // Used in logs
+	Detect  string `json:"detect"`  // CSS selector of an element that is only present while the interstitial is shown
+	Dismiss string `json:"dismiss"` // CSS selector of the element to click to dismiss it
+}
+
+// ConsentInterstitials are the consent management platforms used by many news outlets.
+var ConsentInterstitials = []Interstitial{
+	{Name: "onetrust", Detect: "#onetrust-banner-sdk", Dismiss: "#onetrust-accept-btn-handler"},
+	{Name: "trustarc", Detect: "#truste-consent-track", Dismiss: "#truste-consent-button"},
+	{Name: "didomi", Detect: "#didomi-notice", Dismiss: "#didomi-notice-agree-button"},
+	{Name: "quantcast", Detect: ".qc-cmp2-container", Dismiss: `.qc-cmp2-summary-buttons button[mode="primary"]`},
+	{Name: "usercentrics", Detect: "#usercentrics-root", Dismiss: `[data-testid="uc-accept-all-button"]`},
+}
+
+// Cookie is set in the browser before the provider's first page is loaded.
+type Cookie struct {
+	Name   string
+	Value  string
+	Domain string
+	Path   string
+}
+
+// BrowserSetup prepares a browser tab for a provider's pages. It sets the locale, extra
+// headers and cookies before the first navigation, and dismisses known interstitials
+// while waiting for a page to be ready.
+type BrowserSetup struct {
+	Locale        string            // Sent as the Accept-Language header, e.g. "en-US"
+	Headers       map[string]string // Extra headers sent with every request
+	Cookies       []Cookie
+	Interstitials []Interstitial
+}
+
+// headers returns the extra headers to send with every request.
+func (s BrowserSetup) headers() network.Headers {
+	headers := network.Headers{}
+	for name, value := range s.Headers {
+		headers[name] = value
+	}
+	if s.Locale != "" {
+		headers["Accept-Language"] = s.Locale
+	}
+	return headers
+}
+
+// Prepare sets the headers and cookies. It must run before the first navigation.
+func (s BrowserSetup) Prepare() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if headers := s.headers(); len(headers) > 0 {
+			if err := network.SetExtraHTTPHeaders(headers).Do(ctx); err != nil {
+				return fmt.Errorf("failed to set headers: %w", err)
+			}
+		}
+		for _, cookie := range s.Cookies {
+			set := network.SetCookie(cookie.Name, cookie.Value).WithDomain(cookie.Domain)
+			if cookie.Path != "" {
+				set = set.WithPath(cookie.Path)
+			}
+			if err := set.Do(ctx); err != nil {
+				return fmt.Errorf("failed to set cookie %s: %w", cookie.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// WaitReady waits until an element matching the CSS selector is visible. Known interstitials
+// that show up in the meantime are dismissed, so that they cannot block the page.
+func (s BrowserSetup) WaitReady(sel string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if len(s.Interstitials) > 0 {
+			if err := s.dismissUntilPresent(ctx, sel); err != nil {
+				return err
+			}
+		}
+		return chromedp.WaitVisible(sel, chromedp.ByQuery).Do(ctx)
+	})
+}
+
+// dismissUntilPresent dismisses interstitials until an element matching the CSS selector exists.
+func (s BrowserSetup) dismissUntilPresent(ctx context.Context, sel string) error {
+	script, err := dismissScript(sel, s.Interstitials)
+	if err != nil {
+		return err
+	}
+	for {
+		var state struct {
+			Ready     bool   `json:"ready"`
+			Dismissed string `json:"dismissed"`
+		}
+		if err := chromedp.Evaluate(script, &state).Do(ctx); err != nil {
+			// dismissing a consent wall often reloads the page, which destroys the
+			// execution context the script ran in; try again on the new page
+			if !isNavigationError(err) {
+				return err
+			}
+			log.Debug().Err(err).Msg("page navigated while checking for interstitials")
+		}
+		if state.Dismissed != "" {
+			log.Info().Str("interstitial", state.Dismissed).Msg("dismissed interstitial")
+		}
+		if state.Ready {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interstitialPollInterval):
+		}
+	}
+}
+
+// navigationErrors are the messages of the DevTools protocol when a script is evaluated
+// while the page navigates away.
+var navigationErrors = []string{
+	"Execution context was destroyed",
+	"Cannot find context with specified id",
+	"Cannot find default execution context",
+	"Inspected target navigated or closed",
+}
+
+// isNavigationError reports whether err was caused by the page navigating away.
+func isNavigationError(err error) bool {
+	for _, message := range navigationErrors {
+		if strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+	return false
+}
+
+// dismissScript returns JavaScript that reports whether an element matching the CSS selector
+// exists and otherwise clicks the dismiss element of the first interstitial that is shown.
+func dismissScript(sel string, interstitials []Interstitial) (string, error) {
+	encodedSel, err := json.Marshal(sel)
+	if err != nil {
+		return "", err
+	}
+	encodedInterstitials, err := json.Marshal(interstitials)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`(() => {
+	if (document.querySelector(%s) !== null) {
+		return {ready: true, dismissed: ""};
+	}
+	for (const interstitial of %s) {
+		if (document.querySelector(interstitial.detect) === null) {
+			continue;
+		}
+		const button = document.querySelector(interstitial.dismiss);
+		if (button === null) {
+			continue;
+		}
+		button.click();
+		return {ready: false, dismissed: interstitial.name};
+	}
+	return {ready: false, dismissed: ""};
+})()`, encodedSel, encodedInterstitials), nil
+}
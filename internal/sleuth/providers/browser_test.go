@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chromedp/cdproto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrowserSetupHeaders(t *testing.T) {
+	setup := BrowserSetup{
+		Locale:  "de-DE",
+		Headers: map[string]string{"Accept-Language": "en-US", "DNT": "1"},
+	}
+	headers := setup.headers()
+	require.Equal(t, "de-DE", headers["Accept-Language"])
+	require.Equal(t, "1", headers["DNT"])
+
+	require.Empty(t, BrowserSetup{}.headers())
+}
+
+func TestDismissScriptEscapesSelectors(t *testing.T) {
+	script, err := dismissScript(`div[data-uri^="/_components/card"]`, []Interstitial{
+		{Name: "quantcast", Detect: ".qc-cmp2-container", Dismiss: `button[mode="primary"]`},
+	})
+	require.NoError(t, err)
+	require.Contains(t, script, `document.querySelector("div[data-uri^=\"/_components/card\"]")`)
+	require.Contains(t, script, `"dismiss":"button[mode=\"primary\"]"`)
+}
+
+func TestIsNavigationError(t *testing.T) {
+	require.True(t, isNavigationError(&cdproto.Error{Code: -32000, Message: "Execution context was destroyed."}))
+	require.True(t, isNavigationError(&cdproto.Error{Code: -32000, Message: "Cannot find context with specified id"}))
+	require.False(t, isNavigationError(context.DeadlineExceeded))
+	require.False(t, isNavigationError(errors.New("invalid selector")))
+}
@@ -10,19 +10,31 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/chromedp/chromedp"
 	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/debugartifacts"
 	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
 	"github.com/rs/zerolog/log"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 const ProviderCNN = "cnn"
 
 type providerOption func(*cnnProvider)
 
+// resultsPerPage is the number of results on each page of CNN's search.
+const resultsPerPage = 10
+
 type cnnProvider struct {
 	context        context.Context
 	searchUrl      string
 	withPagination bool
+	browserSetup   providers.BrowserSetup
+}
+
+// DefaultBrowserSetup is how the browser is prepared for CNN's pages unless WithBrowserSetup is used.
+func DefaultBrowserSetup() providers.BrowserSetup {
+	return providers.BrowserSetup{
+		Locale:        "en-US",
+		Interstitials: providers.ConsentInterstitials,
+	}
 }
 
 // Used for testing purposes, to allow the test to serve cnn from custom domain.
@@ -38,11 +50,19 @@ func WithoutPagination() providerOption {
 	}
 }
 
+// WithBrowserSetup replaces the locale, headers, cookies and interstitials used for CNN's pages.
+func WithBrowserSetup(setup providers.BrowserSetup) providerOption {
+	return func(p *cnnProvider) {
+		p.browserSetup = setup
+	}
+}
+
 func NewCNNProvider(ctx context.Context, providerOptions ...providerOption) *cnnProvider {
 	p := &cnnProvider{
 		context:        ctx,
 		searchUrl:      "https://www.cnn.com/search?types=video&q=",
 		withPagination: true,
+		browserSetup:   DefaultBrowserSetup(),
 	}
 	for _, o := range providerOptions {
 		o(p)
@@ -54,51 +74,71 @@ func (p *cnnProvider) ProviderName() string {
 	return ProviderCNN
 }
 
-func (p *cnnProvider) Search(query string) ([]db.Article, error) {
+func (p *cnnProvider) Search(request providers.SearchRequest) (*providers.SearchResult, error) {
 	// Create a chromedp context using the provider's context.
-	ctx, cancel := chromedp.NewContext(p.context)
+	tabCtx, cancel := chromedp.NewContext(p.context)
 	defer cancel()
+	recorder := debugartifacts.Listen(tabCtx, request.DebugDir)
 
 	// Set an overall timeout.
-	ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
+	ctx, cancel := context.WithTimeout(tabCtx, request.SearchTimeout())
 	defer cancel()
 
-	escapedQuery := url.QueryEscape(query)
+	result, err := p.search(ctx, request)
+	if err != nil {
+		recorder.Save(tabCtx, p.ProviderName()+" search "+request.Query, err)
+		return result, err
+	}
+	return result, nil
+}
+
+func (p *cnnProvider) search(ctx context.Context, request providers.SearchRequest) (*providers.SearchResult, error) {
+	escapedQuery := url.QueryEscape(request.Query)
 	searchURL := fmt.Sprintf("%s%s", p.searchUrl, escapedQuery)
+	// Resume by opening the first page that was not completed directly.
+	firstPage := 1
+	if request.ResumeAfterPage > 0 && p.withPagination {
+		firstPage = request.ResumeAfterPage + 1
+		searchURL += fmt.Sprintf("&from=%d&page=%d", request.ResumeAfterPage*resultsPerPage, firstPage)
+	}
 	log.Info().Str("url", searchURL).Msg("Navigating to search URL with chromedp")
 
 	// Navigate to the search page and wait for the results to load.
 	if err := chromedp.Run(ctx,
+		p.browserSetup.Prepare(),
 		chromedp.Navigate(searchURL),
-		chromedp.WaitVisible(`div[data-uri^="/_components/card/instances/search-"]`, chromedp.ByQuery),
+		p.browserSetup.WaitReady(`div[data-uri^="/_components/card/instances/search-"]`),
 	); err != nil {
 		return nil, err
 	}
 
-	var allResults []db.Article
+	result := &providers.SearchResult{}
 
 	// Loop to process each page.
-	for {
+	for page := firstPage; ; page++ {
 		// Extract the full rendered HTML.
 		var renderedHTML string
 		if err := chromedp.Run(ctx,
 			chromedp.OuterHTML("html", &renderedHTML, chromedp.ByQuery),
 		); err != nil {
-			return nil, err
+			return result, err
 		}
 
 		// Parse the HTML using goquery.
 		doc, err := goquery.NewDocumentFromReader(strings.NewReader(renderedHTML))
 		if err != nil {
-			return nil, err
+			return result, err
 		}
 
-		// Extract video details from each card.
+		// Extract video details from each card. The rank of a video is its position among
+		// the videos of the page, like Fox ranks the results it keeps.
+		rank := 0
 		doc.Find(`div[data-uri^="/_components/card/instances/search-"]`).Each(func(i int, s *goquery.Selection) {
 			link, exists := s.Find("a.container__link--type-Video").Attr("href")
 			if !exists || link == "" {
 				return
 			}
+			rank++
 			if strings.HasPrefix(link, "/") {
 				link = "https://www.cnn.com" + link
 			}
@@ -116,18 +156,29 @@ func (p *cnnProvider) Search(query string) ([]db.Article, error) {
 				AiSuggestsDownloadingVideo:        false,
 				Provider:                          p.ProviderName(),
 			}
-			err := db.Models.CreateArticle(p.context, &article)
+			provenance := db.SearchProvenance{
+				Query:    request.Query,
+				QueryId:  request.QueryId,
+				Provider: p.ProviderName(),
+				Page:     page,
+				Rank:     rank,
+				FoundAt:  time.Now(),
+			}
+			created, err := providers.SaveArticle(p.context, &article, provenance)
 			if err != nil {
-				if mongo.IsDuplicateKeyError(err) {
-					log.Warn().Str("url", article.Url).Msg("video already exists in database, skipping")
-				} else {
-					log.Error().Err(err).Msg("Failed to save video to database")
-				}
+				log.Error().Err(err).Msg("Failed to save video to database")
+				return
+			}
+			if !created {
+				log.Warn().Str("url", article.Url).Msg("video already exists in database, recorded provenance")
+				result.Duplicates++
 				return
 			}
 			log.Debug().Str("title", article.Title).Str("provider", p.ProviderName()).Str("date", article.Date).Str("url", article.Url).Msg("Found video")
-			allResults = append(allResults, article)
+			result.Articles = append(result.Articles, article)
 		})
+		result.Pages++
+		request.PageDone(page)
 
 		// Check if a "Next" button is available by verifying if the element with active classes exists.
 		var hasNext bool
@@ -135,7 +186,7 @@ func (p *cnnProvider) Search(query string) ([]db.Article, error) {
 		if err := chromedp.Run(ctx,
 			chromedp.Evaluate(evaluateJS, &hasNext),
 		); err != nil {
-			return nil, err
+			return result, err
 		}
 
 		// If no next page or pagination is disabled, break out of the loop.
@@ -148,13 +199,13 @@ func (p *cnnProvider) Search(query string) ([]db.Article, error) {
 			chromedp.Click(`div.pagination-arrow.pagination-arrow-right.search__pagination-link.text-active`, chromedp.ByQuery),
 			// Give the page time to load the new results.
 			chromedp.Sleep(2*time.Second),
-			chromedp.WaitVisible(`div[data-uri^="/_components/card/instances/search-"]`, chromedp.ByQuery),
+			p.browserSetup.WaitReady(`div[data-uri^="/_components/card/instances/search-"]`),
 		); err != nil {
-			return nil, err
+			return result, err
 		}
 	}
 
-	return allResults, nil
+	return result, nil
 }
 
 // ensure that CNN implements the Provider interface
@@ -3,8 +3,12 @@ package cnn
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,8 +23,9 @@ func TestCNN(t *testing.T) {
 		ctx := t.Context()
 		baseUrl := testServer.URL + "/search.html?q="
 		cnn := NewCNNProvider(ctx, WithCustomSearchUrl(baseUrl), WithoutPagination())
-		videos, err := cnn.Search("body found")
+		result, err := cnn.Search(providers.SearchRequest{Query: "body found"})
 		require.NoError(t, err)
+		videos := result.Articles
 		require.Len(t, videos, 9)
 
 		require.Equal(t, "Passengers say cabin crew put a dead body next to them on flight", videos[0].Title)
@@ -31,3 +36,37 @@ func TestCNN(t *testing.T) {
 	})
 
 }
+
+func TestExtractRelatedVideos(t *testing.T) {
+	f, err := os.Open("testdata/cnn/search.html")
+	require.NoError(t, err)
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	require.NoError(t, err)
+
+	self := "https://www.cnn.com/2025/02/26/world/video/body-on-plane-qatar-airways-digvid?iid=share"
+	related := extractRelatedVideos(doc, self)
+	require.Len(t, related, 8)
+	for _, article := range related {
+		require.NotEqual(t, "https://www.cnn.com/2025/02/26/world/video/body-on-plane-qatar-airways-digvid", article.Url)
+		require.Contains(t, article.Url, "/video/")
+		require.NotContains(t, article.Url, "subscription")
+		require.Equal(t, ProviderCNN, article.Provider)
+	}
+}
+
+func TestExtractRelatedVideosOfVideoPage(t *testing.T) {
+	f, err := os.Open("testdata/cnn/video.html")
+	require.NoError(t, err)
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	require.NoError(t, err)
+
+	// the up next and related rails, without the video itself, section pages, articles and duplicates
+	related := extractRelatedVideos(doc, "https://www.cnn.com/2025/02/26/world/video/body-on-plane-qatar-airways-digvid")
+	require.Equal(t, []db.Article{
+		{Url: "https://www.cnn.com/2025/02/25/us/video/lake-michigan-remains-found-digvid", Title: "Remains found in Lake Michigan identified as missing kayaker", Provider: ProviderCNN},
+		{Url: "https://www.cnn.com/2025/02/24/us/video/missing-hiker-found-colorado-lon-orig", Title: "Search crews find missing hiker in Colorado", Provider: ProviderCNN},
+		{Url: "https://www.cnn.com/2025/02/20/world/video/body-found-amazon-river-brazil-digvid", Title: "Body found in Amazon river a week after boat capsized", Provider: ProviderCNN},
+	}, related)
+}
@@ -0,0 +1,109 @@
+package cnn
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
+	"github.com/rs/zerolog/log"
+)
+
+// videoPathPattern matches the path of a dated CNN video page, e.g. /2025/02/26/world/video/<slug>.
+var videoPathPattern = regexp.MustCompile(`^/\d{4}/\d{2}/\d{2}/.+/video/`)
+
+// Related loads a CNN video page and returns the related and up next videos linked from it.
+func (p *cnnProvider) Related(articleUrl string) ([]db.Article, error) {
+	ctx, cancel := chromedp.NewContext(p.context)
+	defer cancel()
+
+	ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	log.Info().Str("url", articleUrl).Msg("Navigating to video page with chromedp")
+
+	var renderedHTML string
+	if err := chromedp.Run(ctx,
+		p.browserSetup.Prepare(),
+		chromedp.Navigate(articleUrl),
+		p.browserSetup.WaitReady(`div[data-video-id]`),
+		// Give the related video rails time to render.
+		chromedp.Sleep(2*time.Second),
+		chromedp.OuterHTML("html", &renderedHTML, chromedp.ByQuery),
+	); err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(renderedHTML))
+	if err != nil {
+		return nil, err
+	}
+	return extractRelatedVideos(doc, articleUrl), nil
+}
+
+// extractRelatedVideos collects every link to another CNN video page in the document.
+func extractRelatedVideos(doc *goquery.Document, articleUrl string) []db.Article {
+	seen := map[string]struct{}{normalizeVideoUrl(articleUrl): {}}
+	var related []db.Article
+
+	doc.Find(`a[href*="/video/"]`).Each(func(i int, s *goquery.Selection) {
+		link, _ := s.Attr("href")
+		link = normalizeVideoUrl(link)
+		if link == "" {
+			return
+		}
+		if _, found := seen[link]; found {
+			return
+		}
+		seen[link] = struct{}{}
+
+		// the first link of a card is often its thumbnail, the headline is in another link of the card
+		title := strings.TrimSpace(s.Find("span.container__headline-text").Text())
+		if title == "" {
+			title = strings.TrimSpace(s.Closest(".card").Find("span.container__headline-text").First().Text())
+		}
+		if title == "" {
+			title = strings.TrimSpace(s.Text())
+		}
+
+		related = append(related, db.Article{
+			Url:      link,
+			Title:    title,
+			Provider: ProviderCNN,
+		})
+	})
+	return related
+}
+
+// normalizeVideoUrl makes a CNN video link absolute and strips query strings and fragments,
+// so the same video is not stored twice under different tracking parameters.
+// It returns an empty string for links that do not point to a CNN video page.
+func normalizeVideoUrl(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return ""
+	}
+	if u.Host == "" {
+		u.Scheme = "https"
+		u.Host = "www.cnn.com"
+	}
+	if u.Host != "www.cnn.com" && u.Host != "cnn.com" {
+		return ""
+	}
+	if !videoPathPattern.MatchString(u.Path) {
+		return ""
+	}
+	u.Scheme = "https"
+	u.Host = "www.cnn.com"
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// ensure that CNN can crawl related videos
+var _ providers.RelatedProvider = &cnnProvider{}
@@ -10,9 +10,9 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/chromedp/chromedp"
 	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/debugartifacts"
 	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
 	"github.com/rs/zerolog/log"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 const ProviderFoxNews = "foxnews"
@@ -20,8 +20,18 @@ const ProviderFoxNews = "foxnews"
 type foxProviderOption func(*foxProvider)
 
 type foxProvider struct {
+	context        context.Context
 	searchUrl      string
 	withPagination bool
+	browserSetup   providers.BrowserSetup
+}
+
+// DefaultBrowserSetup is how the browser is prepared for Fox News' pages unless WithBrowserSetup is used.
+func DefaultBrowserSetup() providers.BrowserSetup {
+	return providers.BrowserSetup{
+		Locale:        "en-US",
+		Interstitials: providers.ConsentInterstitials,
+	}
 }
 
 func WithCustomSearchUrl(url string) foxProviderOption {
@@ -36,10 +46,19 @@ func WithoutPagination() foxProviderOption {
 	}
 }
 
-func NewFoxProvider(providerOptions ...foxProviderOption) *foxProvider {
+// WithBrowserSetup replaces the locale, headers, cookies and interstitials used for Fox News' pages.
+func WithBrowserSetup(setup providers.BrowserSetup) foxProviderOption {
+	return func(p *foxProvider) {
+		p.browserSetup = setup
+	}
+}
+
+func NewFoxProvider(ctx context.Context, providerOptions ...foxProviderOption) *foxProvider {
 	p := &foxProvider{
+		context:        ctx,
 		searchUrl:      "https://www.foxnews.com/search-results/search#q=",
 		withPagination: true,
+		browserSetup:   DefaultBrowserSetup(),
 	}
 	for _, o := range providerOptions {
 		o(p)
@@ -51,33 +70,45 @@ func (p *foxProvider) ProviderName() string {
 	return ProviderFoxNews
 }
 
-func (p *foxProvider) Search(query string) ([]db.Article, error) {
-	// Create a chromedp context.
-	ctx, cancel := chromedp.NewContext(context.Background())
+func (p *foxProvider) Search(request providers.SearchRequest) (*providers.SearchResult, error) {
+	// Create a chromedp context using the provider's context.
+	tabCtx, cancel := chromedp.NewContext(p.context)
 	defer cancel()
+	recorder := debugartifacts.Listen(tabCtx, request.DebugDir)
+	artifactsName := p.ProviderName() + " search " + request.Query
 
 	// Set an overall timeout.
-	ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
+	ctx, cancel := context.WithTimeout(tabCtx, request.SearchTimeout())
 	defer cancel()
 
-	escapedQuery := url.QueryEscape(query)
+	escapedQuery := url.QueryEscape(request.Query)
 	searchURL := fmt.Sprintf("%s%s", p.searchUrl, escapedQuery)
 	log.Info().Str("url", searchURL).Msg("Navigating to Fox News search URL with chromedp")
 
 	// Navigate to the search URL and wait until at least one article is visible.
 	if err := chromedp.Run(ctx,
+		p.browserSetup.Prepare(),
 		chromedp.Navigate(searchURL),
-		chromedp.WaitVisible(`article.article`, chromedp.ByQuery),
+		p.browserSetup.WaitReady(`article.article`),
 	); err != nil {
+		recorder.Save(tabCtx, artifactsName, err)
 		return nil, err
 	}
 
 	// Use a map to deduplicate articles by URL.
 	seen := make(map[string]struct{})
-	var allResults []db.Article
+	result := &providers.SearchResult{}
 
 	// extractArticles parses the provided HTML and appends new articles.
-	extractArticles := func(html string) {
+	// Every "Load More" click counts as a new page, and the rank of an article
+	// is its position among the articles that page added. When resuming, the
+	// pages completed earlier are clicked through again but only marked as seen.
+	extractArticles := func(html string, page int) {
+		resumed := page <= request.ResumeAfterPage
+		if !resumed {
+			result.Pages++
+		}
+		rank := 0
 		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 		if err != nil {
 			log.Error().Err(err).Msg("failed to create goquery document")
@@ -96,6 +127,10 @@ func (p *foxProvider) Search(query string) ([]db.Article, error) {
 				return
 			}
 			seen[link] = struct{}{}
+			rank++
+			if resumed {
+				return
+			}
 
 			// Extract title from the <h2 class="title"><a> element.
 			title := strings.TrimSpace(s.Find("h2.title a").Text())
@@ -113,18 +148,30 @@ func (p *foxProvider) Search(query string) ([]db.Article, error) {
 				AiSuggestsDownloadingVideo:        false,
 				Provider:                          ProviderFoxNews,
 			}
-			err := db.Models.CreateArticle(ctx, &article)
+			provenance := db.SearchProvenance{
+				Query:    request.Query,
+				QueryId:  request.QueryId,
+				Provider: p.ProviderName(),
+				Page:     page,
+				Rank:     rank,
+				FoundAt:  time.Now(),
+			}
+			created, err := providers.SaveArticle(ctx, &article, provenance)
 			if err != nil {
-				if mongo.IsDuplicateKeyError(err) {
-					log.Warn().Str("url", article.Url).Msg("video already exists in database, skipping")
-				} else {
-					log.Error().Err(err).Msg("Failed to save video to database")
-				}
+				log.Error().Err(err).Msg("Failed to save video to database")
+				return
+			}
+			if !created {
+				log.Warn().Str("url", article.Url).Msg("video already exists in database, recorded provenance")
+				result.Duplicates++
 				return
 			}
 			log.Debug().Str("title", article.Title).Str("provider", p.ProviderName()).Str("date", article.Date).Str("url", article.Url).Msg("Found video")
-			allResults = append(allResults, article)
+			result.Articles = append(result.Articles, article)
 		})
+		if !resumed {
+			request.PageDone(page)
+		}
 	}
 
 	// Get the initial rendered HTML.
@@ -132,18 +179,20 @@ func (p *foxProvider) Search(query string) ([]db.Article, error) {
 	if err := chromedp.Run(ctx,
 		chromedp.OuterHTML("html", &renderedHTML, chromedp.ByQuery),
 	); err != nil {
+		recorder.Save(tabCtx, artifactsName, err)
 		return nil, err
 	}
-	extractArticles(renderedHTML)
+	extractArticles(renderedHTML, 1)
 
-	// Loop to click "Load More" until the button is no longer present.
+	// Loop to click "Load More" until the button is no longer present. A page that fails to load
+	// fails the search, so that it is resumed after the last completed page.
 	if p.withPagination {
-		for {
+		for page := 2; ; page++ {
 			var loadMoreExists bool
 			checkJS := `document.querySelector('div.button.load-more a') !== null`
 			if err := chromedp.Run(ctx, chromedp.Evaluate(checkJS, &loadMoreExists)); err != nil {
-				log.Error().Err(err).Msg("failed to evaluate load more existence")
-				break
+				recorder.Save(tabCtx, artifactsName, err)
+				return result, fmt.Errorf("failed to evaluate load more existence after page %d: %w", page-1, err)
 			}
 			if !loadMoreExists {
 				break
@@ -154,22 +203,22 @@ func (p *foxProvider) Search(query string) ([]db.Article, error) {
 				chromedp.Click(`div.button.load-more a`, chromedp.ByQuery),
 				chromedp.Sleep(2*time.Second), // wait for the new articles to load
 			); err != nil {
-				log.Error().Err(err).Msg("failed to click load more")
-				break
+				recorder.Save(tabCtx, artifactsName, err)
+				return result, fmt.Errorf("failed to click load more after page %d: %w", page-1, err)
 			}
 
 			// Get the updated HTML.
 			if err := chromedp.Run(ctx,
 				chromedp.OuterHTML("html", &renderedHTML, chromedp.ByQuery),
 			); err != nil {
-				log.Error().Err(err).Msg("failed to get updated HTML")
-				break
+				recorder.Save(tabCtx, artifactsName, err)
+				return result, fmt.Errorf("failed to get updated HTML after page %d: %w", page-1, err)
 			}
-			extractArticles(renderedHTML)
+			extractArticles(renderedHTML, page)
 		}
 	}
 
-	return allResults, nil
+	return result, nil
 }
 
 // ensure foxProvider implements the Provider interface
@@ -1,10 +1,74 @@
 package providers
 
 import (
+	"context"
+	"time"
+
 	"github.com/giraffesyo/sleuth/internal/db"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// DefaultTimeout is how long a search may take when the request does not set a timeout.
+const DefaultTimeout = 60 * time.Second
+
+// SearchRequest describes a single search to run against a provider.
+type SearchRequest struct {
+	Query           string
+	QueryId         primitive.ObjectID // Set when the search terms came from the queries collection
+	ResumeAfterPage int                // Pages completed by an earlier, interrupted search, which are skipped
+	OnPage          func(page int)     // Called after each page of results has been saved, to checkpoint progress
+	Timeout         time.Duration      // Overall timeout of the search, DefaultTimeout if zero
+	DebugDir        string             // Directory to save debug artifacts to when the search fails, none are saved if empty
+}
+
+// SearchTimeout returns the overall timeout of the search.
+func (r SearchRequest) SearchTimeout() time.Duration {
+	if r.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return r.Timeout
+}
+
+// PageDone reports that every result on the page has been saved.
+func (r SearchRequest) PageDone(page int) {
+	if r.OnPage != nil {
+		r.OnPage(page)
+	}
+}
+
+// SearchResult is what a provider found for a single search.
+type SearchResult struct {
+	Articles   []db.Article // Articles that were not in the database before this search
+	Duplicates int          // Number of results that were already in the database
+	Pages      int          // Number of result pages visited
+}
+
 type Provider interface {
-	Search(query string) ([]db.Article, error)
+	Search(request SearchRequest) (*SearchResult, error)
 	ProviderName() string
 }
+
+// RelatedProvider is implemented by providers whose article pages link to
+// related videos. The returned articles are not saved to the database.
+type RelatedProvider interface {
+	Provider
+	Related(articleUrl string) ([]db.Article, error)
+}
+
+// SaveArticle stores an article found by a search together with its provenance entry.
+// If the article already exists, the provenance entry is appended to the stored article
+// instead and created is false.
+func SaveArticle(ctx context.Context, article *db.Article, provenance db.SearchProvenance) (created bool, err error) {
+	article.Provenance = []db.SearchProvenance{provenance}
+	err = db.Models.CreateArticle(ctx, article)
+	if err == nil {
+		return true, nil
+	}
+	if !db.IsDuplicateKeyError(err) {
+		return false, err
+	}
+	if err := db.Models.AddArticleProvenance(ctx, article.Url, provenance); err != nil {
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,88 @@
+// Package querytemplate expands query templates such as "{setting} body found {state}"
+// into every combination of the values given for their variables.
+package querytemplate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrNoVariables = errors.New("template has no variables")
+
+// variablePattern matches a variable such as {state} in a template.
+var variablePattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Expansion is one query produced from a template, with the values that were substituted.
+type Expansion struct {
+	Query  string
+	Values map[string]string
+}
+
+// Variables returns the names of the variables in a template, in order of first appearance.
+func Variables(template string) []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, match := range variablePattern.FindAllStringSubmatch(template, -1) {
+		if _, found := seen[match[1]]; found {
+			continue
+		}
+		seen[match[1]] = struct{}{}
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// Expand substitutes every combination of values into the template. The last variable varies fastest.
+// Every variable in the template needs at least one value, and values for variables the template
+// does not use are rejected. Whitespace is collapsed and duplicate queries are only returned once.
+func Expand(template string, values map[string][]string) ([]Expansion, error) {
+	names := Variables(template)
+	if len(names) == 0 {
+		return nil, ErrNoVariables
+	}
+	used := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		used[name] = struct{}{}
+		if len(values[name]) == 0 {
+			return nil, fmt.Errorf("no values for variable %q", name)
+		}
+	}
+	for name := range values {
+		if _, found := used[name]; !found {
+			return nil, fmt.Errorf("variable %q is not used in the template", name)
+		}
+	}
+
+	var expansions []Expansion
+	seen := make(map[string]struct{})
+	indices := make([]int, len(names))
+	for {
+		substituted := make(map[string]string, len(names))
+		for i, name := range names {
+			substituted[name] = strings.TrimSpace(values[name][indices[i]])
+		}
+		query := variablePattern.ReplaceAllStringFunc(template, func(v string) string {
+			return substituted[v[1:len(v)-1]]
+		})
+		query = strings.Join(strings.Fields(query), " ")
+		if _, found := seen[query]; !found && query != "" {
+			seen[query] = struct{}{}
+			expansions = append(expansions, Expansion{Query: query, Values: substituted})
+		}
+
+		// advance to the next combination, like an odometer
+		i := len(names) - 1
+		for ; i >= 0; i-- {
+			indices[i]++
+			if indices[i] < len(values[names[i]]) {
+				break
+			}
+			indices[i] = 0
+		}
+		if i < 0 {
+			return expansions, nil
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package querytemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariables(t *testing.T) {
+	require.Equal(t, []string{"setting", "state"}, Variables("{setting} body found {state} {setting}"))
+	require.Empty(t, Variables("body found in lake"))
+}
+
+func TestExpand(t *testing.T) {
+	expansions, err := Expand("{setting} body found {state}", map[string][]string{
+		"setting": {"lake", "woods"},
+		"state":   {"Texas", "Ohio", ""},
+	})
+	require.NoError(t, err)
+
+	var queries []string
+	for _, e := range expansions {
+		queries = append(queries, e.Query)
+	}
+	require.Equal(t, []string{
+		"lake body found Texas",
+		"lake body found Ohio",
+		"lake body found",
+		"woods body found Texas",
+		"woods body found Ohio",
+		"woods body found",
+	}, queries)
+	require.Equal(t, map[string]string{"setting": "woods", "state": "Ohio"}, expansions[4].Values)
+}
+
+func TestExpandErrors(t *testing.T) {
+	_, err := Expand("body found", nil)
+	require.ErrorIs(t, err, ErrNoVariables)
+
+	_, err = Expand("{setting} body found", map[string][]string{})
+	require.ErrorContains(t, err, `no values for variable "setting"`)
+
+	_, err = Expand("{setting} body found", map[string][]string{"setting": {"lake"}, "state": {"Ohio"}})
+	require.ErrorContains(t, err, `variable "state" is not used`)
+}
@@ -3,19 +3,31 @@ package sleuth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/giraffesyo/sleuth/internal/db"
 	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
 	"github.com/giraffesyo/sleuth/internal/sleuth/providers/cnn"
 	"github.com/giraffesyo/sleuth/internal/sleuth/providers/fox"
+	"github.com/giraffesyo/sleuth/internal/version"
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type sleuth struct {
 	enabledProviders []string
-	query            string
+	queries          []string
+	unusedQueries    bool
+	resumeRunId      primitive.ObjectID
+	timeout          time.Duration
+	debugDir         string
+	newProvider      func(ctx context.Context, name string) providers.Provider
 }
 
 type sleuthOption func(*sleuth)
@@ -26,20 +38,62 @@ func WithProvider(p ...string) sleuthOption {
 	}
 }
 
-func WithSearchQuery(query string) sleuthOption {
+func WithSearchQueries(queries ...string) sleuthOption {
 	return func(s *sleuth) {
-		s.query = query
+		s.queries = append(s.queries, queries...)
+	}
+}
+
+// WithUnusedQueries runs every query in the queries collection that has not been used yet when enabled is true.
+func WithUnusedQueries(enabled bool) sleuthOption {
+	return func(s *sleuth) {
+		s.unusedQueries = enabled
+	}
+}
+
+// WithResume continues an interrupted run instead of starting a new one.
+// The queries and providers of the run are used instead of the configured ones.
+// A zero run ID starts a new run as usual.
+func WithResume(runId primitive.ObjectID) sleuthOption {
+	return func(s *sleuth) {
+		s.resumeRunId = runId
+	}
+}
+
+// WithTimeout sets the overall timeout of each provider's search for a query.
+func WithTimeout(timeout time.Duration) sleuthOption {
+	return func(s *sleuth) {
+		s.timeout = timeout
+	}
+}
+
+// WithDebugArtifacts saves debug artifacts of failed searches to a directory of the run inside dir.
+func WithDebugArtifacts(dir string) sleuthOption {
+	return func(s *sleuth) {
+		s.debugDir = dir
 	}
 }
 
 func NewSleuth(options ...sleuthOption) *sleuth {
-	s := &sleuth{}
+	s := &sleuth{newProvider: newProvider}
 	for _, o := range options {
 		o(s)
 	}
 	return s
 }
 
+// newProvider returns the provider registered under name, or nil if there is none.
+func newProvider(ctx context.Context, name string) providers.Provider {
+	switch name {
+	case cnn.ProviderCNN:
+		return cnn.NewCNNProvider(ctx)
+	case fox.ProviderFoxNews:
+		return fox.NewFoxProvider(ctx)
+	default:
+		return nil
+	}
+}
+
 func writeVideosToFile(provider string, videos []db.Article) error {
 	jsonData, err := json.MarshalIndent(videos, "", "  ")
 	if err != nil {
@@ -53,43 +107,272 @@ func writeVideosToFile(provider string, videos []db.Article) error {
 	return nil
 }
 
-func (s *sleuth) Run() error {
-	ctx := context.Background()
-	if s.query == "" {
-		return ErrEmptySearchQuery
+// resolveQueries returns the stored query for every search term the sleuth should run.
+// Search terms that are not in the queries collection yet are added to it as ad hoc queries, so that
+// their runs can be recorded without them counting as generated queries.
+func (s *sleuth) resolveQueries(ctx context.Context) ([]*db.Query, error) {
+	var queries []*db.Query
+	seen := make(map[string]struct{})
+
+	if s.unusedQueries {
+		unused, err := db.Models.FindUnusedQueries(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find unused queries: %w", err)
+		}
+		for _, q := range unused {
+			seen[q.Query] = struct{}{}
+			queries = append(queries, q)
+		}
+	}
+
+	for _, term := range s.queries {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		if _, found := seen[term]; found {
+			continue
+		}
+		seen[term] = struct{}{}
+
+		query, err := db.Models.FindQueryByValue(ctx, term)
+		if err != nil {
+			query = &db.Query{
+				Query:       term,
+				Description: "Provided to the search command",
+				AdHoc:       true,
+			}
+			if err := db.Models.CreateQuery(ctx, query); err != nil {
+				return nil, fmt.Errorf("failed to save query %q: %w", term, err)
+			}
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+// startRun records a new run, or reopens the run being resumed.
+func startRun(ctx context.Context, resumed *db.SearchRun, queries []*db.Query, enabled []providers.Provider) (*db.SearchRun, error) {
+	if resumed != nil {
+		run := resumed
+		if err := db.Models.ResumeRun(ctx, run.Id, time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to resume run: %w", err)
+		}
+		log.Info().Str("run", run.Id.Hex()).Msg("resumed run")
+		return run, nil
+	}
+
+	run := &db.SearchRun{Version: version.Version}
+	for _, query := range queries {
+		run.Queries = append(run.Queries, query.Query)
+	}
+	for _, provider := range enabled {
+		run.Providers = append(run.Providers, provider.ProviderName())
+	}
+	if err := db.Models.CreateRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to save run: %w", err)
+	}
+	log.Info().Str("run", run.Id.Hex()).Msg("started run")
+	return run, nil
+}
+
+// Run searches the enabled providers for every query. The providers are searched concurrently,
+// and a provider that fails does not stop the others; its error is recorded in the summary.
+// The returned error is only set when the run itself could not be carried out or recorded.
+//
+// Each provider checkpoints its pagination position on the run after every page. When resuming
+// a run, its queries and providers are used, searches that finished are skipped and the others
+// continue after their last completed page. Cancelling ctx stops the searches that are running.
+func (s *sleuth) Run(ctx context.Context) (*Summary, error) {
+	if len(s.queries) == 0 && !s.unusedQueries && s.resumeRunId.IsZero() {
+		return nil, ErrEmptySearchQuery
 	}
 
 	// initialize the database client
 
-	uri := db.GetMongoURI()
-	if err := db.Models.ConnectDatabase(uri); err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+	uri := db.GetDatabaseURI()
+	if err := db.Connect(uri); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	var resumed *db.SearchRun
+	var checkpoints map[string]db.RunCheckpoint
+	if !s.resumeRunId.IsZero() {
+		run, err := db.Models.FindRunByID(ctx, s.resumeRunId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find run to resume: %w", err)
+		}
+		resumed = run
+		s.queries = run.Queries
+		s.unusedQueries = false
+		s.enabledProviders = run.Providers
+		checkpoints = run.Checkpoints
+	}
+
+	queries, err := s.resolveQueries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	if len(queries) == 0 {
+		log.Info().Msg("no queries to run")
+		return summary, nil
 	}
 
-	log.Info().Str("query", s.query).Msg("searching for news articles")
+	var enabled []providers.Provider
 	for _, p := range s.enabledProviders {
-		var provider providers.Provider
-		switch p {
-		case cnn.ProviderCNN:
-			log.Info().Msg("CNN is enabled")
-			provider = cnn.NewCNNProvider(ctx)
-		case fox.ProviderFoxNews:
-			log.Info().Msg("Fox News is enabled")
-			provider = fox.NewFoxProvider()
-		default:
+		provider := s.newProvider(ctx, p)
+		if provider == nil {
 			log.Warn().Str("provider", p).Msg("unknown provider, skipping")
 			continue
 		}
-		videos, err := provider.Search(s.query)
-		if err != nil {
-			return fmt.Errorf("failed to search CNN: %w", err)
+		log.Info().Str("provider", provider.ProviderName()).Msg("provider is enabled")
+		enabled = append(enabled, provider)
+	}
+
+	// record the run as it progresses
+	run, err := startRun(ctx, resumed, queries, enabled)
+	if err != nil {
+		return nil, err
+	}
+	summary.RunId = run.Id
+	var debugDir string
+	if s.debugDir != "" {
+		debugDir = filepath.Join(s.debugDir, run.Id.Hex())
+	}
+
+	// the progress of the run is recorded even once ctx is cancelled, so that it can be resumed
+	recordCtx := context.WithoutCancel(ctx)
+
+	var errs []error
+	for i, query := range queries {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("run stopped before %q: %w", query.Query, ctx.Err()))
+			break
+		}
+		log.Info().Str("query", query.Query).Int("current", i+1).Int("total", len(queries)).Msg("searching for news articles")
+		queryRun := db.QueryRun{RanAt: time.Now()}
+
+		var pending []providers.Provider
+		var requests []providers.SearchRequest
+		for _, provider := range enabled {
+			checkpoint := checkpoints[db.CheckpointKey(provider.ProviderName(), query.Id)]
+			if checkpoint.Done {
+				log.Info().Str("provider", provider.ProviderName()).Str("query", query.Query).Msg("search already finished, skipping")
+				continue
+			}
+			pending = append(pending, provider)
+			request := s.searchRequest(recordCtx, run.Id, query, provider.ProviderName(), checkpoint.Page)
+			request.DebugDir = debugDir
+			requests = append(requests, request)
 		}
-		log.Info().Str("provider", provider.ProviderName()).Int("count", len(videos)).Msg("search results")
+		results := searchProviders(pending, requests)
+		summary.Results = append(summary.Results, results...)
+
+		failed := false
+		searches := make([]db.RunSearch, 0, len(results))
+		for j, result := range results {
+			searches = append(searches, result.runSearch(query))
+			if result.Err != nil {
+				log.Err(result.Err).Str("provider", result.Provider).Str("query", query.Query).Msg("search failed")
+				failed = true
+				continue
+			}
+			queryRun.Providers = append(queryRun.Providers, result.Provider)
+			queryRun.NewArticles += len(result.Articles)
+			queryRun.DuplicateArticles += result.Duplicates
+
+			done := db.RunCheckpoint{
+				Query:    query.Query,
+				QueryId:  query.Id,
+				Provider: result.Provider,
+				Page:     requests[j].ResumeAfterPage + result.Pages,
+				Done:     true,
+			}
+			if err := db.Models.SaveRunCheckpoint(recordCtx, run.Id, done); err != nil {
+				errs = append(errs, fmt.Errorf("failed to checkpoint %s for %q: %w", result.Provider, query.Query, err))
+			}
+		}
+		if len(searches) > 0 {
+			if err := db.Models.AddRunSearches(recordCtx, run.Id, searches); err != nil {
+				errs = append(errs, fmt.Errorf("failed to record searches for %q: %w", query.Query, err))
+			}
+		}
+		// leave the query unused so that it is picked up again by the next batch
+		if failed || len(searches) == 0 {
+			continue
+		}
+		if err := db.Models.RecordQueryRun(recordCtx, query.Id, queryRun); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record run of %q: %w", query.Query, err))
+		}
+	}
+
+	videos := make(map[string][]db.Article)
+	for _, result := range summary.Results {
+		videos[result.Provider] = append(videos[result.Provider], result.Articles...)
+	}
+	for _, provider := range enabled {
 		// write videos to file
-		err = writeVideosToFile(provider.ProviderName(), videos)
+		err := writeVideosToFile(provider.ProviderName(), videos[provider.ProviderName()])
 		if err != nil {
-			return fmt.Errorf("failed to write videos to file: %w", err)
+			errs = append(errs, fmt.Errorf("failed to write videos to file: %w", err))
 		}
 	}
-	return nil
+
+	var runErrors []string
+	for _, err := range errs {
+		runErrors = append(runErrors, err.Error())
+	}
+	if err := db.Models.FinishRun(recordCtx, run.Id, time.Now(), runErrors); err != nil {
+		errs = append(errs, fmt.Errorf("failed to record end of run: %w", err))
+	}
+	log.Info().Str("run", run.Id.Hex()).Msg("finished run")
+	return summary, errors.Join(errs...)
+}
+
+// searchRequest builds the request for a provider's search, which checkpoints every completed page on the run.
+func (s *sleuth) searchRequest(ctx context.Context, runId primitive.ObjectID, query *db.Query, provider string, resumeAfterPage int) providers.SearchRequest {
+	return providers.SearchRequest{
+		Query:           query.Query,
+		QueryId:         query.Id,
+		ResumeAfterPage: resumeAfterPage,
+		Timeout:         s.timeout,
+		OnPage: func(page int) {
+			checkpoint := db.RunCheckpoint{
+				Query:    query.Query,
+				QueryId:  query.Id,
+				Provider: provider,
+				Page:     page,
+			}
+			if err := db.Models.SaveRunCheckpoint(ctx, runId, checkpoint); err != nil {
+				log.Err(err).Str("provider", provider).Str("query", query.Query).Int("page", page).Msg("failed to save checkpoint")
+			}
+		},
+	}
+}
+
+// searchProviders runs the search of every provider at once and waits for all of them to finish.
+// requests holds the request for the provider at the same index, and the results are in the same order.
+func searchProviders(enabled []providers.Provider, requests []providers.SearchRequest) []ProviderResult {
+	results := make([]ProviderResult, len(enabled))
+	var wg sync.WaitGroup
+	for i, provider := range enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = ProviderResult{Provider: provider.ProviderName(), Query: requests[i].Query}
+			found, err := provider.Search(requests[i])
+			results[i].Err = err
+			// a search that fails part way returns the articles it saved before the failure
+			if found == nil {
+				return
+			}
+			log.Info().Str("provider", provider.ProviderName()).Int("count", len(found.Articles)).Int("duplicates", found.Duplicates).Msg("search results")
+			results[i].Articles = found.Articles
+			results[i].Duplicates = found.Duplicates
+			results[i].Pages = found.Pages
+		}()
+	}
+	wg.Wait()
+	return results
 }
@@ -0,0 +1,76 @@
+package sleuth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedProvider completes pages of results, and fails after failAfter pages if it is set.
+type pagedProvider struct {
+	pages     int
+	failAfter int
+	requests  []providers.SearchRequest
+}
+
+func (p *pagedProvider) ProviderName() string {
+	return "paged"
+}
+
+func (p *pagedProvider) Search(request providers.SearchRequest) (*providers.SearchResult, error) {
+	p.requests = append(p.requests, request)
+	result := &providers.SearchResult{}
+	for page := request.ResumeAfterPage + 1; page <= p.pages; page++ {
+		if p.failAfter > 0 && page > p.failAfter {
+			return result, errors.New("load more timed out")
+		}
+		result.Pages++
+		request.PageDone(page)
+	}
+	return result, nil
+}
+
+func TestResumeFailedSearch(t *testing.T) {
+	t.Chdir(t.TempDir())
+	db.Models = db.NewMemory()
+	t.Cleanup(func() { db.Models = nil })
+	ctx := context.Background()
+
+	provider := &pagedProvider{pages: 5, failAfter: 2}
+	withProvider := func(s *sleuth) {
+		s.newProvider = func(context.Context, string) providers.Provider { return provider }
+	}
+
+	// a search that fails part way is reported and its checkpoint is left at the last completed page
+	summary, err := NewSleuth(WithProvider("paged"), WithSearchQueries("body found in lake"), withProvider).Run(ctx)
+	require.NoError(t, err)
+	require.True(t, summary.Failed())
+	run, err := db.Models.FindRunByID(ctx, summary.RunId)
+	require.NoError(t, err)
+	query, err := db.Models.FindQueryByValue(ctx, "body found in lake")
+	require.NoError(t, err)
+	require.False(t, query.Used)
+	checkpoint := run.Checkpoints[db.CheckpointKey("paged", query.Id)]
+	require.Equal(t, 2, checkpoint.Page)
+	require.False(t, checkpoint.Done)
+
+	// resuming continues after the last completed page
+	provider.failAfter = 0
+	summary, err = NewSleuth(WithResume(run.Id), withProvider).Run(ctx)
+	require.NoError(t, err)
+	require.False(t, summary.Failed())
+	require.Len(t, provider.requests, 2)
+	require.Equal(t, 2, provider.requests[1].ResumeAfterPage)
+	run, err = db.Models.FindRunByID(ctx, run.Id)
+	require.NoError(t, err)
+	checkpoint = run.Checkpoints[db.CheckpointKey("paged", query.Id)]
+	require.Equal(t, 5, checkpoint.Page)
+	require.True(t, checkpoint.Done)
+	query, err = db.Models.FindQueryByValue(ctx, "body found in lake")
+	require.NoError(t, err)
+	require.True(t, query.Used)
+}
@@ -0,0 +1,116 @@
+package sleuth
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProviderResult is the outcome of searching one provider for one query.
+type ProviderResult struct {
+	Provider   string       `json:"provider"`
+	Query      string       `json:"query"`
+	Articles   []db.Article `json:"-"`
+	Duplicates int          `json:"duplicates"`
+	Pages      int          `json:"pages"`
+	Err        error        `json:"-"`
+}
+
+// runSearch converts the result into the record stored on the run.
+func (r ProviderResult) runSearch(query *db.Query) db.RunSearch {
+	search := db.RunSearch{
+		Query:       query.Query,
+		QueryId:     query.Id,
+		Provider:    r.Provider,
+		Pages:       r.Pages,
+		NewArticles: len(r.Articles),
+		Duplicates:  r.Duplicates,
+	}
+	for _, article := range r.Articles {
+		search.ArticleIds = append(search.ArticleIds, article.Id)
+	}
+	if r.Err != nil {
+		search.Error = r.Err.Error()
+	}
+	return search
+}
+
+// ProviderSummary totals the results of one provider over every query of a run.
+type ProviderSummary struct {
+	Provider    string            `json:"provider"`
+	Searches    int               `json:"searches"`
+	NewArticles int               `json:"newArticles"`
+	Duplicates  int               `json:"duplicates"`
+	Failures    map[string]string `json:"failures,omitempty"` // Error of each failed search, by query
+}
+
+// Summary collects the result of every search of a run.
+type Summary struct {
+	RunId   primitive.ObjectID // The run recorded in the runs collection
+	Results []ProviderResult
+}
+
+// Failed reports whether any provider failed to search for any query.
+func (s *Summary) Failed() bool {
+	for _, r := range s.Results {
+		if r.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Articles returns every new article found by the run.
+func (s *Summary) Articles() []db.Article {
+	var articles []db.Article
+	for _, r := range s.Results {
+		articles = append(articles, r.Articles...)
+	}
+	return articles
+}
+
+// ByProvider totals the results per provider, in the order the providers first appear.
+func (s *Summary) ByProvider() []ProviderSummary {
+	var summaries []ProviderSummary
+	index := make(map[string]int)
+	for _, r := range s.Results {
+		i, found := index[r.Provider]
+		if !found {
+			i = len(summaries)
+			index[r.Provider] = i
+			summaries = append(summaries, ProviderSummary{Provider: r.Provider})
+		}
+		p := &summaries[i]
+		p.Searches++
+		p.NewArticles += len(r.Articles)
+		p.Duplicates += r.Duplicates
+		if r.Err != nil {
+			if p.Failures == nil {
+				p.Failures = make(map[string]string)
+			}
+			p.Failures[r.Query] = r.Err.Error()
+		}
+	}
+	return summaries
+}
+
+// Write prints a table with the totals of each provider, followed by the errors of failed searches.
+func (s *Summary) Write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if !s.RunId.IsZero() {
+		fmt.Fprintf(w, "Run %s\n\n", s.RunId.Hex())
+	}
+	fmt.Fprintln(tw, "PROVIDER\tSEARCHES\tNEW\tDUPLICATES\tFAILED")
+	for _, p := range s.ByProvider() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", p.Provider, p.Searches, p.NewArticles, p.Duplicates, len(p.Failures))
+	}
+	tw.Flush()
+	for _, r := range s.Results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s failed for %q: %v\n", r.Provider, r.Query, r.Err)
+		}
+	}
+}
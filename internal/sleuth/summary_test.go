@@ -0,0 +1,50 @@
+package sleuth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/giraffesyo/sleuth/internal/sleuth/providers"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	name   string
+	result *providers.SearchResult
+	err    error
+}
+
+func (p *fakeProvider) Search(request providers.SearchRequest) (*providers.SearchResult, error) {
+	return p.result, p.err
+}
+
+func (p *fakeProvider) ProviderName() string {
+	return p.name
+}
+
+func TestSearchProvidersIsolatesFailures(t *testing.T) {
+	enabled := []providers.Provider{
+		&fakeProvider{name: "broken", err: errors.New("selector not found")},
+		&fakeProvider{name: "working", result: &providers.SearchResult{Articles: []db.Article{{Url: "a"}, {Url: "b"}}, Duplicates: 3}},
+	}
+
+	request := providers.SearchRequest{Query: "body found"}
+	summary := &Summary{Results: searchProviders(enabled, []providers.SearchRequest{request, request})}
+	require.True(t, summary.Failed())
+	require.Len(t, summary.Articles(), 2)
+
+	byProvider := summary.ByProvider()
+	require.Len(t, byProvider, 2)
+	require.Equal(t, "broken", byProvider[0].Provider)
+	require.Equal(t, map[string]string{"body found": "selector not found"}, byProvider[0].Failures)
+	require.Equal(t, "working", byProvider[1].Provider)
+	require.Equal(t, 2, byProvider[1].NewArticles)
+	require.Equal(t, 3, byProvider[1].Duplicates)
+	require.Empty(t, byProvider[1].Failures)
+
+	var out strings.Builder
+	summary.Write(&out)
+	require.Contains(t, out.String(), `broken failed for "body found": selector not found`)
+}
@@ -0,0 +1,189 @@
+package sleuth
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Yield counts what the articles first found by a query went on to produce.
+type Yield struct {
+	NewArticles    int     `json:"newArticles"`    // Articles first found by the query
+	Checked        int     `json:"checked"`        // New articles the AI check has looked at
+	Approved       int     `json:"approved"`       // New articles the AI check suggested downloading
+	ApprovedShare  float64 `json:"approvedShare"`  // Approved divided by checked new articles
+	Downloaded     int     `json:"downloaded"`     // New articles whose video file exists on disk
+	WithTimestamps int     `json:"withTimestamps"` // New articles with at least one relevant timestamp
+}
+
+// higherThan ranks yields by approved articles, then articles with relevant timestamps,
+// then downloaded videos and finally new articles.
+func (y Yield) higherThan(other Yield) bool {
+	if y.Approved != other.Approved {
+		return y.Approved > other.Approved
+	}
+	if y.WithTimestamps != other.WithTimestamps {
+		return y.WithTimestamps > other.WithTimestamps
+	}
+	if y.Downloaded != other.Downloaded {
+		return y.Downloaded > other.Downloaded
+	}
+	return y.NewArticles > other.NewArticles
+}
+
+func (y *Yield) computeShare() {
+	if y.Checked > 0 {
+		y.ApprovedShare = float64(y.Approved) / float64(y.Checked)
+	}
+}
+
+// QueryYield is the yield of a single query.
+// An article counts towards the query in its first provenance entry only, so that
+// queries which keep finding the same articles are not credited twice.
+type QueryYield struct {
+	QueryId        primitive.ObjectID `json:"queryId"`
+	Query          string             `json:"query"`
+	Used           bool               `json:"used"`
+	Runs           int                `json:"runs"`
+	TemplateId     primitive.ObjectID `json:"templateId,omitempty"`
+	TemplateValues map[string]string  `json:"templateValues,omitempty"`
+	Yield
+}
+
+// VariableYield sums the yields of the queries expanded with the same value for a template variable.
+type VariableYield struct {
+	Variable string `json:"variable"`
+	Value    string `json:"value"`
+	Queries  int    `json:"queries"`
+	Yield
+}
+
+// QueryYields computes the yield of every stored query, highest yield first. Ad hoc queries given
+// to the search command are left out, as they were not chosen by generation or templates.
+func QueryYields(ctx context.Context) ([]QueryYield, error) {
+	queries, err := db.Models.FindAllQueries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find queries: %w", err)
+	}
+	var scanErr error
+	articles := func(yield func(*db.Article) bool) {
+		filter := bson.M{"provenance": bson.M{"$exists": true}}
+		for article, err := range db.Models.ScanArticles(ctx, filter, db.ScanOptions{Projection: yieldProjection}) {
+			if err != nil {
+				scanErr = err
+				return
+			}
+			if !yield(article) {
+				return
+			}
+		}
+	}
+	yields := computeQueryYields(queries, articles)
+	if scanErr != nil {
+		return nil, fmt.Errorf("failed to find articles: %w", scanErr)
+	}
+	return yields, nil
+}
+
+// yieldProjection selects the fields of an article that computeQueryYields reads.
+var yieldProjection = bson.M{
+	"provenance":                        1,
+	"aiHasCheckedIfShouldDownloadVideo": 1,
+	"aiSuggestsDownloadingVideo":        1,
+	"videoPath":                         1,
+	"relevantTimestamps":                1,
+}
+
+// computeQueryYields computes the yield of every query that is not ad hoc, highest yield first.
+func computeQueryYields(queries []*db.Query, articles iter.Seq[*db.Article]) []QueryYield {
+	queries = slices.DeleteFunc(slices.Clone(queries), func(q *db.Query) bool { return q.AdHoc })
+	yields := make([]QueryYield, len(queries))
+	byId := make(map[primitive.ObjectID]*QueryYield, len(queries))
+	for i, q := range queries {
+		yields[i] = QueryYield{
+			QueryId:        q.Id,
+			Query:          q.Query,
+			Used:           q.Used,
+			Runs:           len(q.Runs),
+			TemplateId:     q.TemplateId,
+			TemplateValues: q.TemplateValues,
+		}
+		byId[q.Id] = &yields[i]
+	}
+
+	for article := range articles {
+		if len(article.Provenance) == 0 {
+			continue
+		}
+		y, found := byId[article.Provenance[0].QueryId]
+		if !found {
+			continue
+		}
+		y.NewArticles++
+		if article.AiHasCheckedIfShouldDownloadVideo {
+			y.Checked++
+		}
+		if article.AiSuggestsDownloadingVideo {
+			y.Approved++
+		}
+		if article.VideoPath != "" {
+			if _, err := os.Stat(article.VideoPath); err == nil {
+				y.Downloaded++
+			}
+		}
+		if len(article.RelevantTimestamps) > 0 {
+			y.WithTimestamps++
+		}
+	}
+
+	for i := range yields {
+		yields[i].computeShare()
+	}
+
+	sort.SliceStable(yields, func(i, j int) bool {
+		return yields[i].higherThan(yields[j].Yield)
+	})
+	return yields
+}
+
+// GroupYieldsByVariable sums query yields per value of a template variable, highest yield first.
+// Queries that were not expanded from a template with that variable are left out.
+func GroupYieldsByVariable(yields []QueryYield, variable string) []VariableYield {
+	var grouped []VariableYield
+	byValue := make(map[string]int)
+	for _, y := range yields {
+		value, found := y.TemplateValues[variable]
+		if !found {
+			continue
+		}
+		i, found := byValue[value]
+		if !found {
+			i = len(grouped)
+			byValue[value] = i
+			grouped = append(grouped, VariableYield{Variable: variable, Value: value})
+		}
+		g := &grouped[i]
+		g.Queries++
+		g.NewArticles += y.NewArticles
+		g.Checked += y.Checked
+		g.Approved += y.Approved
+		g.Downloaded += y.Downloaded
+		g.WithTimestamps += y.WithTimestamps
+	}
+
+	for i := range grouped {
+		grouped[i].computeShare()
+	}
+
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return grouped[i].higherThan(grouped[j].Yield)
+	})
+	return grouped
+}
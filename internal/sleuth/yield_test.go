@@ -0,0 +1,72 @@
+package sleuth
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/giraffesyo/sleuth/internal/db"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestComputeQueryYields(t *testing.T) {
+	lake := &db.Query{Id: primitive.NewObjectID(), Query: "body found in lake", Used: true, Runs: []db.QueryRun{{}}}
+	woods := &db.Query{Id: primitive.NewObjectID(), Query: "remains discovered woods", Used: true}
+	unused := &db.Query{Id: primitive.NewObjectID(), Query: "missing hiker"}
+	adHoc := &db.Query{Id: primitive.NewObjectID(), Query: "qatar airways body", Used: true, AdHoc: true}
+
+	foundBy := func(queries ...*db.Query) []db.SearchProvenance {
+		var provenance []db.SearchProvenance
+		for _, q := range queries {
+			provenance = append(provenance, db.SearchProvenance{QueryId: q.Id})
+		}
+		return provenance
+	}
+	articles := []*db.Article{
+		{Provenance: foundBy(woods), AiHasCheckedIfShouldDownloadVideo: true, AiSuggestsDownloadingVideo: true, RelevantTimestamps: []db.RelevantTimestamp{{Start: "00:01"}}},
+		{Provenance: foundBy(woods, lake), AiHasCheckedIfShouldDownloadVideo: true},
+		{Provenance: foundBy(lake), AiHasCheckedIfShouldDownloadVideo: true},
+		{Provenance: foundBy(lake)},
+		{Provenance: nil},
+		{Provenance: foundBy(adHoc), AiHasCheckedIfShouldDownloadVideo: true, AiSuggestsDownloadingVideo: true},
+	}
+
+	// ad hoc queries have no yield
+	yields := computeQueryYields([]*db.Query{lake, woods, unused, adHoc}, slices.Values(articles))
+	require.Len(t, yields, 3)
+
+	require.Equal(t, woods.Id, yields[0].QueryId)
+	require.Equal(t, 2, yields[0].NewArticles)
+	require.Equal(t, 1, yields[0].Approved)
+	require.Equal(t, 0.5, yields[0].ApprovedShare)
+	require.Equal(t, 1, yields[0].WithTimestamps)
+
+	require.Equal(t, lake.Id, yields[1].QueryId)
+	require.Equal(t, 2, yields[1].NewArticles)
+	require.Equal(t, 0, yields[1].Approved)
+	require.Equal(t, 1, yields[1].Runs)
+
+	require.Equal(t, unused.Id, yields[2].QueryId)
+	require.Equal(t, 0, yields[2].NewArticles)
+}
+
+func TestGroupYieldsByVariable(t *testing.T) {
+	yields := []QueryYield{
+		{Query: "lake body found Texas", TemplateValues: map[string]string{"setting": "lake", "state": "Texas"}, Yield: Yield{NewArticles: 4, Checked: 4, Approved: 1}},
+		{Query: "woods body found Texas", TemplateValues: map[string]string{"setting": "woods", "state": "Texas"}, Yield: Yield{NewArticles: 2, Checked: 2, Approved: 2}},
+		{Query: "lake body found Ohio", TemplateValues: map[string]string{"setting": "lake", "state": "Ohio"}, Yield: Yield{NewArticles: 3, Checked: 2, Approved: 2}},
+		{Query: "body found in lake", Yield: Yield{NewArticles: 10, Checked: 10, Approved: 10}},
+	}
+
+	grouped := GroupYieldsByVariable(yields, "setting")
+	require.Len(t, grouped, 2)
+	require.Equal(t, "lake", grouped[0].Value)
+	require.Equal(t, 2, grouped[0].Queries)
+	require.Equal(t, 7, grouped[0].NewArticles)
+	require.Equal(t, 3, grouped[0].Approved)
+	require.Equal(t, 0.5, grouped[0].ApprovedShare)
+	require.Equal(t, "woods", grouped[1].Value)
+	require.Equal(t, 1.0, grouped[1].ApprovedShare)
+
+	require.Empty(t, GroupYieldsByVariable(yields, "season"))
+}
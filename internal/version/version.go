@@ -0,0 +1,5 @@
+// Package version holds the version of the sleuth CLI, recorded with everything it collects.
+package version
+
+// Version can be overridden at build time with -ldflags "-X github.com/giraffesyo/sleuth/internal/version.Version=..."
+var Version = "v0.0.1"